@@ -23,6 +23,53 @@ const (
 	MONTHLY_FLAG = "monthly"
 	YEARLY_FLAG  = "yearly"
 	DRYRUN_FLAG  = "dry-run"
+
+	TIMESTAMP_PATTERN_FLAG = "timestamp-pattern"
+	TIMESTAMP_SOURCE_FLAG  = "timestamp-source"
+	TIMEZONE_FLAG          = "timezone"
+	WEEK_START_FLAG        = "week-start"
+	MODE_FLAG              = "mode"
+	SELECT_FLAG            = "select"
+
+	MINUTELY_FLAG          = "minutely"
+	MINUTELY_INTERVAL_FLAG = "minutely-interval"
+	QUARTERLY_FLAG         = "quarterly"
+	FISCAL_YEAR_START_FLAG = "fiscal-year-start"
+	TIERS_FLAG             = "tiers"
+	KEEP_LAST_FLAG         = "keep-last"
+	KEEP_WITHIN_FLAG       = "keep-within"
+	KEEP_WITHIN_DAILY_FLAG = "keep-within-daily"
+
+	EXPONENTIAL_BASE_FLAG    = "exponential-base"
+	EXPONENTIAL_MAX_AGE_FLAG = "exponential-max-age"
+	HANOI_LEVELS_FLAG        = "hanoi-levels"
+	HANOI_INTERVAL_FLAG      = "hanoi-interval"
+
+	POLICY_FLAG       = "policy"
+	POLICY_SHORT_FLAG = "p"
+
+	GROUP_BY_FLAG       = "group-by"
+	GROUP_BY_SHORT_FLAG = "g"
+
+	PER_DIRECTORY_FLAG       = "per-directory"
+	PER_DIRECTORY_DEPTH_FLAG = "per-directory-depth"
+
+	SIDECARS_FLAG = "sidecars"
+
+	INCREMENTAL_FLAG = "incremental"
+	PARENT_FLAG      = "parent"
+
+	INCLUDE_FLAG  = "include"
+	EXCLUDE_FLAG  = "exclude"
+	MIN_SIZE_FLAG = "min-size"
+
+	RAW_PREFIX_FLAG = "raw-prefix"
+	MAX_DEPTH_FLAG  = "max-depth"
+
+	DIRECTORIES_FLAG       = "directories"
+	REMOVE_EMPTY_DIRS_FLAG = "remove-empty-dirs"
+	QUIET_PERIOD_FLAG      = "quiet-period"
+	SETTLE_INTERVAL_FLAG   = "settle-interval"
 )
 
 const (
@@ -32,6 +79,10 @@ const (
 	MONTHLY_SHORT_FLAG = "m"
 	YEARLY_SHORT_FLAG  = "y"
 	DRYRUN_SHORT_FLAG  = "D"
+
+	TIMESTAMP_PATTERN_SHORT_FLAG = "t"
+	TIMESTAMP_SOURCE_SHORT_FLAG  = "s"
+	TIMEZONE_SHORT_FLAG          = "z"
 )
 
 const (
@@ -40,4 +91,26 @@ const (
 	DEFAULT_WEEKLY  = 14
 	DEFAULT_MONTHLY = 12
 	DEFAULT_YEARLY  = -1
+
+	DEFAULT_TIMESTAMP_SOURCE = "default"
+	DEFAULT_TIMEZONE         = "Local"
+	DEFAULT_WEEK_START       = "sunday"
+	DEFAULT_MODE             = "rolling"
+	DEFAULT_SELECT           = "default"
+
+	DEFAULT_MINUTELY          = 0
+	DEFAULT_MINUTELY_INTERVAL = 5
+	DEFAULT_QUARTERLY         = 0
+	DEFAULT_FISCAL_YEAR_START = 1
+	DEFAULT_KEEP_LAST         = 0
+
+	DEFAULT_EXPONENTIAL_BASE = "1h"
+	DEFAULT_HANOI_LEVELS     = 5
+	DEFAULT_HANOI_INTERVAL   = "1d"
+
+	DEFAULT_PER_DIRECTORY_DEPTH = 1
+	DEFAULT_MAX_DEPTH           = 0
 )
+
+// NONE is the default of optional string flags, since commando requires string flags with an empty default.
+const NONE = "none"
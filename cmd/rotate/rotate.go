@@ -18,6 +18,7 @@ package main
 
 import (
 	"strings"
+	_ "time/tzdata"
 
 	"github.com/joho/godotenv"
 	"github.com/raniellyferreira/rotate-files/internal/version"
@@ -68,6 +69,176 @@ func main() {
 			"simulate deletion process",
 			commando.Bool,
 			false).
+		AddFlag(
+			strings.Join([]string{TIMESTAMP_PATTERN_FLAG, TIMESTAMP_PATTERN_SHORT_FLAG}, ","),
+			"derive timestamps from paths, e.g. db-%Y%m%d-%H%M.sql.gz, backups/%Y/%m/%d/ or a regexp with named groups",
+			commando.String,
+			NONE).
+		AddFlag(
+			strings.Join([]string{TIMESTAMP_SOURCE_FLAG, TIMESTAMP_SOURCE_SHORT_FLAG}, ","),
+			"timestamp source: default, modified, created, metadata:<key> or tag:<key>, falling back to modified then created",
+			commando.String,
+			DEFAULT_TIMESTAMP_SOURCE).
+		AddFlag(
+			strings.Join([]string{TIMEZONE_FLAG, TIMEZONE_SHORT_FLAG}, ","),
+			"IANA time zone of the backup calendar, e.g. America/Sao_Paulo",
+			commando.String,
+			DEFAULT_TIMEZONE).
+		AddFlag(
+			WEEK_START_FLAG,
+			"day calendar weeks start on, e.g. monday for ISO weeks",
+			commando.String,
+			DEFAULT_WEEK_START).
+		AddFlag(
+			MODE_FLAG,
+			"rotation mode: rolling keeps files within windows relative to now, calendar keeps one file per calendar hour, day, week, month and year, exponential keeps one file per doubling age interval, hanoi keeps a Tower of Hanoi rotation",
+			commando.String,
+			DEFAULT_MODE).
+		AddFlag(
+			SELECT_FLAG,
+			"backup representing each period: newest, oldest or largest, for every tier or per tier as in monthly=oldest,daily=largest; default is newest, oldest in calendar mode",
+			commando.String,
+			DEFAULT_SELECT).
+		AddFlag(
+			MINUTELY_FLAG,
+			"number of minutely backups to preserve, one per minutely interval",
+			commando.Int,
+			DEFAULT_MINUTELY).
+		AddFlag(
+			MINUTELY_INTERVAL_FLAG,
+			"minutes of each minutely period, e.g. 5 or 15",
+			commando.Int,
+			DEFAULT_MINUTELY_INTERVAL).
+		AddFlag(
+			QUARTERLY_FLAG,
+			"number of quarterly backups to preserve",
+			commando.Int,
+			DEFAULT_QUARTERLY).
+		AddFlag(
+			FISCAL_YEAR_START_FLAG,
+			"month (1-12) quarters and years start on, e.g. 4 for fiscal years starting in April",
+			commando.Int,
+			DEFAULT_FISCAL_YEAR_START).
+		AddFlag(
+			TIERS_FLAG,
+			"user-defined tiers as duration:count pairs, e.g. 6h:8,1d:14,30d:24,365d:10, replacing the built-in tier flags",
+			commando.String,
+			NONE).
+		AddFlag(
+			KEEP_LAST_FLAG,
+			"number of most recent backups to always preserve",
+			commando.Int,
+			DEFAULT_KEEP_LAST).
+		AddFlag(
+			KEEP_WITHIN_FLAG,
+			"preserve every backup newer than a duration, e.g. 48h or 7d",
+			commando.String,
+			NONE).
+		AddFlag(
+			KEEP_WITHIN_DAILY_FLAG,
+			"preserve one backup per day newer than a duration, e.g. 30d",
+			commando.String,
+			NONE).
+		AddFlag(
+			EXPONENTIAL_BASE_FLAG,
+			"first age interval of the exponential mode, doubling after each interval",
+			commando.String,
+			DEFAULT_EXPONENTIAL_BASE).
+		AddFlag(
+			EXPONENTIAL_MAX_AGE_FLAG,
+			"age above which the exponential mode deletes backups, e.g. 1y",
+			commando.String,
+			NONE).
+		AddFlag(
+			HANOI_LEVELS_FLAG,
+			"number of levels of the hanoi mode, the number of backups it preserves",
+			commando.Int,
+			DEFAULT_HANOI_LEVELS).
+		AddFlag(
+			HANOI_INTERVAL_FLAG,
+			"length of each slot of the hanoi mode, e.g. 1d",
+			commando.String,
+			DEFAULT_HANOI_INTERVAL).
+		AddFlag(
+			strings.Join([]string{POLICY_FLAG, POLICY_SHORT_FLAG}, ","),
+			"retention policy replacing the tier and keep flags, e.g. hourly=24,daily=30,yearly=forever,keep-last=3, or a preset: gfs-standard, compliance-7y",
+			commando.String,
+			NONE).
+		AddFlag(
+			strings.Join([]string{GROUP_BY_FLAG, GROUP_BY_SHORT_FLAG}, ","),
+			"rotate each backup family on its own, grouping files by name stem (stem) or by a regexp capture group, e.g. ^([a-z]+)-",
+			commando.String,
+			NONE).
+		AddFlag(
+			PER_DIRECTORY_FLAG,
+			"rotate each subdirectory or sub-prefix of the path on its own, with the same policy",
+			commando.Bool,
+			false).
+		AddFlag(
+			PER_DIRECTORY_DEPTH_FLAG,
+			"number of directory levels below the path making up each backup set in per-directory mode",
+			commando.Int,
+			DEFAULT_PER_DIRECTORY_DEPTH).
+		AddFlag(
+			SIDECARS_FLAG,
+			"keep or delete related files as one set, linked by shared stem (stem) or by sidecar suffixes, e.g. .sha256,.manifest.json",
+			commando.String,
+			NONE).
+		AddFlag(
+			INCREMENTAL_FLAG,
+			"regexp matching incremental backups, e.g. incr; kept incrementals keep the backups they depend on",
+			commando.String,
+			NONE).
+		AddFlag(
+			PARENT_FLAG,
+			"regexp whose first capture group references the parent of an incremental, e.g. from-(\\d{8}); chained by time when unset",
+			commando.String,
+			NONE).
+		AddFlag(
+			INCLUDE_FLAG,
+			"comma-separated globs or re: regexps of the files to rotate, e.g. *.sql.gz or re:^db-\\d{2,4}; other files are never deleted",
+			commando.String,
+			NONE).
+		AddFlag(
+			EXCLUDE_FLAG,
+			"comma-separated globs or re: regexps of files never rotated nor deleted, e.g. *.tmp,*.part,README*",
+			commando.String,
+			NONE).
+		AddFlag(
+			MIN_SIZE_FLAG,
+			"minimum size of the files to rotate, e.g. 1K or 10MB; smaller files are never deleted",
+			commando.String,
+			NONE).
+		AddFlag(
+			RAW_PREFIX_FLAG,
+			"list every cloud object whose key starts with the path as given, instead of the objects within the path as a directory",
+			commando.Bool,
+			false).
+		AddFlag(
+			MAX_DEPTH_FLAG,
+			"how many directory levels below the path to list, 1 for its direct children only, 0 for the whole tree",
+			commando.Int,
+			DEFAULT_MAX_DEPTH).
+		AddFlag(
+			DIRECTORIES_FLAG,
+			"rotate each subdirectory or sub-prefix of the path as one backup, deleting its whole tree",
+			commando.Bool,
+			false).
+		AddFlag(
+			REMOVE_EMPTY_DIRS_FLAG,
+			"remove the directories below a local path left empty by the deleted files, never the path itself",
+			commando.Bool,
+			false).
+		AddFlag(
+			QUIET_PERIOD_FLAG,
+			"skip the local files modified within a duration such as 10m as in progress",
+			commando.String,
+			NONE).
+		AddFlag(
+			SETTLE_INTERVAL_FLAG,
+			"wait a duration such as 5s after listing, skipping the local files whose size changed meanwhile as in progress",
+			commando.String,
+			NONE).
 		SetAction(HandlerRotate)
 
 	commando.Parse(nil)
@@ -35,20 +35,150 @@ func HandlerRotate(args map[string]commando.ArgValue, flags map[string]commando.
 	path := args["path"].Value
 	log.Println("Starting rotation on", path)
 
+	minutelyInt, _ := flags[MINUTELY_FLAG].GetInt()
+	minutelyIntervalInt, _ := flags[MINUTELY_INTERVAL_FLAG].GetInt()
 	hourlyInt, _ := flags[HOURLY_FLAG].GetInt()
 	dailyInt, _ := flags[DAILY_FLAG].GetInt()
 	weeklyInt, _ := flags[WEEKLY_FLAG].GetInt()
 	monthlyInt, _ := flags[MONTHLY_FLAG].GetInt()
+	quarterlyInt, _ := flags[QUARTERLY_FLAG].GetInt()
 	yearlyInt, _ := flags[YEARLY_FLAG].GetInt()
+	fiscalYearStartInt, _ := flags[FISCAL_YEAR_START_FLAG].GetInt()
 	dryRunBool, _ := flags[DRYRUN_FLAG].GetBool()
+	timestampPatternString, _ := flags[TIMESTAMP_PATTERN_FLAG].GetString()
+	timestampSourceString, _ := flags[TIMESTAMP_SOURCE_FLAG].GetString()
+	timezoneString, _ := flags[TIMEZONE_FLAG].GetString()
+	weekStartString, _ := flags[WEEK_START_FLAG].GetString()
+	modeString, _ := flags[MODE_FLAG].GetString()
+	selectString, _ := flags[SELECT_FLAG].GetString()
+	tiersString, _ := flags[TIERS_FLAG].GetString()
+	keepLastInt, _ := flags[KEEP_LAST_FLAG].GetInt()
+	keepWithinString, _ := flags[KEEP_WITHIN_FLAG].GetString()
+	keepWithinDailyString, _ := flags[KEEP_WITHIN_DAILY_FLAG].GetString()
+	exponentialBaseString, _ := flags[EXPONENTIAL_BASE_FLAG].GetString()
+	exponentialMaxAgeString, _ := flags[EXPONENTIAL_MAX_AGE_FLAG].GetString()
+	hanoiLevelsInt, _ := flags[HANOI_LEVELS_FLAG].GetInt()
+	hanoiIntervalString, _ := flags[HANOI_INTERVAL_FLAG].GetString()
+	policyString, _ := flags[POLICY_FLAG].GetString()
+	groupByString, _ := flags[GROUP_BY_FLAG].GetString()
+	perDirectoryBool, _ := flags[PER_DIRECTORY_FLAG].GetBool()
+	perDirectoryDepthInt, _ := flags[PER_DIRECTORY_DEPTH_FLAG].GetInt()
+	sidecarsString, _ := flags[SIDECARS_FLAG].GetString()
+	incrementalString, _ := flags[INCREMENTAL_FLAG].GetString()
+	parentString, _ := flags[PARENT_FLAG].GetString()
+	includeString, _ := flags[INCLUDE_FLAG].GetString()
+	excludeString, _ := flags[EXCLUDE_FLAG].GetString()
+	minSizeString, _ := flags[MIN_SIZE_FLAG].GetString()
+	rawPrefixBool, _ := flags[RAW_PREFIX_FLAG].GetBool()
+	maxDepthInt, _ := flags[MAX_DEPTH_FLAG].GetInt()
+	directoriesBool, _ := flags[DIRECTORIES_FLAG].GetBool()
+	removeEmptyDirsBool, _ := flags[REMOVE_EMPTY_DIRS_FLAG].GetBool()
+	quietPeriodString, _ := flags[QUIET_PERIOD_FLAG].GetString()
+	settleIntervalString, _ := flags[SETTLE_INTERVAL_FLAG].GetString()
+
+	weekStartsAt, err := rotate.ParseWeekStart(weekStartString)
+	if err != nil {
+		log.Fatal("Invalid week start:", err)
+	}
+
+	selection, err := rotate.ParseTierSelection(selectString)
+	if err != nil {
+		log.Fatal("Invalid selection:", err)
+	}
+
+	if minutelyIntervalInt < 1 {
+		log.Fatal("Invalid minutely interval:", minutelyIntervalInt)
+	}
+
+	if fiscalYearStartInt < 1 || fiscalYearStartInt > 12 {
+		log.Fatal("Invalid fiscal year start:", fiscalYearStartInt)
+	}
+
+	if maxDepthInt < 0 {
+		log.Fatal("Invalid max depth:", maxDepthInt)
+	}
 
 	rotationScheme := &rotate.RotationScheme{
-		Hourly:  hourlyInt,
-		Daily:   dailyInt,
-		Weekly:  weeklyInt,
-		Monthly: monthlyInt,
-		Yearly:  yearlyInt,
-		DryRun:  dryRunBool,
+		Minutely:         minutelyInt,
+		MinutelyInterval: minutelyIntervalInt,
+		Hourly:           hourlyInt,
+		Daily:            dailyInt,
+		Weekly:           weeklyInt,
+		Monthly:          monthlyInt,
+		Quarterly:        quarterlyInt,
+		Yearly:           yearlyInt,
+		FiscalYearStart:  fiscalYearStartInt,
+		DryRun:           dryRunBool,
+		Timezone:         timezoneString,
+		WeekStartsAt:     weekStartsAt,
+		Mode:             modeString,
+		Selection:        selection,
+		KeepLast:         keepLastInt,
+		HanoiLevels:      hanoiLevelsInt,
+	}
+
+	if keepWithinString != NONE {
+		if rotationScheme.KeepWithin, err = rotate.ParseDuration(keepWithinString); err != nil {
+			log.Fatal("Invalid keep within:", err)
+		}
+	}
+
+	if keepWithinDailyString != NONE {
+		if rotationScheme.KeepWithinDaily, err = rotate.ParseDuration(keepWithinDailyString); err != nil {
+			log.Fatal("Invalid keep within daily:", err)
+		}
+	}
+
+	if rotationScheme.ExponentialBase, err = rotate.ParseDuration(exponentialBaseString); err != nil {
+		log.Fatal("Invalid exponential base:", err)
+	}
+
+	if exponentialMaxAgeString != NONE {
+		if rotationScheme.ExponentialMaxAge, err = rotate.ParseDuration(exponentialMaxAgeString); err != nil {
+			log.Fatal("Invalid exponential max age:", err)
+		}
+	}
+
+	if rotationScheme.HanoiInterval, err = rotate.ParseDuration(hanoiIntervalString); err != nil {
+		log.Fatal("Invalid hanoi interval:", err)
+	}
+
+	if tiersString != NONE {
+		tiers, err := rotate.ParseTiers(tiersString)
+		if err != nil {
+			log.Fatal("Invalid tiers:", err)
+		}
+		rotationScheme.Tiers = tiers
+		rotationScheme.Minutely, rotationScheme.Hourly, rotationScheme.Daily = 0, 0, 0
+		rotationScheme.Weekly, rotationScheme.Monthly, rotationScheme.Quarterly, rotationScheme.Yearly = 0, 0, 0, 0
+	}
+
+	if policyString != NONE {
+		policy, err := rotate.ParsePolicy(policyString)
+		if err != nil {
+			log.Fatal("Invalid policy:", err)
+		}
+		if policy.MinutelyInterval == 0 {
+			policy.MinutelyInterval = rotationScheme.MinutelyInterval
+		}
+		if policy.FiscalYearStart == 0 {
+			policy.FiscalYearStart = rotationScheme.FiscalYearStart
+		}
+		policy.DryRun = rotationScheme.DryRun
+		policy.Timezone = rotationScheme.Timezone
+		policy.WeekStartsAt = rotationScheme.WeekStartsAt
+		policy.Mode = rotationScheme.Mode
+		policy.Selection = rotationScheme.Selection
+		policy.ExponentialBase = rotationScheme.ExponentialBase
+		policy.ExponentialMaxAge = rotationScheme.ExponentialMaxAge
+		policy.HanoiLevels = rotationScheme.HanoiLevels
+		policy.HanoiInterval = rotationScheme.HanoiInterval
+		rotationScheme = policy
+		log.Println("Using policy", rotationScheme)
+	}
+
+	if _, err := rotationScheme.Location(); err != nil {
+		log.Fatal("Invalid timezone:", err)
 	}
 
 	if rotationScheme.DryRun {
@@ -60,21 +190,120 @@ func HandlerRotate(args map[string]commando.ArgValue, flags map[string]commando.
 		log.Fatal("Failed to initialize provider:", err)
 	}
 
+	if _, ok := provider.(providers.EmptyDirectoryRemover); removeEmptyDirsBool && !ok {
+		log.Fatal("Invalid remove empty dirs: not supported on", path)
+	}
+
+	var policy rotate.Policy = rotationScheme
+	if incrementalString != NONE {
+		if parentString == NONE {
+			parentString = ""
+		}
+		chainRule, err := rotate.ParseChainRule(incrementalString, parentString)
+		if err != nil {
+			log.Fatal("Invalid chain rule:", err)
+		}
+		policy = rotate.Chained(chainRule, policy)
+	} else if parentString != NONE {
+		log.Fatal("Invalid chain rule: --parent requires --incremental")
+	}
+
+	if groupByString != NONE {
+		groupBy, err := rotate.ParseGroupBy(groupByString)
+		if err != nil {
+			log.Fatal("Invalid group by:", err)
+		}
+		policy = rotate.PerGroup(groupBy, policy)
+	}
+
+	if perDirectoryBool {
+		if perDirectoryDepthInt < 1 {
+			log.Fatal("Invalid per-directory depth:", perDirectoryDepthInt)
+		}
+		policy = rotate.PerGroup(rotate.GroupByDirectory(path, perDirectoryDepthInt), policy)
+	}
+
+	if sidecarsString != NONE {
+		linkBy, err := rotate.ParseLinkBy(sidecarsString)
+		if err != nil {
+			log.Fatal("Invalid sidecars:", err)
+		}
+		policy = rotate.Linked(linkBy, policy)
+	}
+
 	manager := rotate.NewRotationManager(
 		provider,
-		rotationScheme,
+		policy,
 		path,
 	)
 
+	timestampSource, err := providers.ParseTimestampSource(timestampSourceString)
+	if err != nil {
+		log.Fatal("Invalid timestamp source:", err)
+	}
+	listOptions := providers.ListOptions{
+		TimestampSource: timestampSource,
+		RawPrefix:       rawPrefixBool,
+		MaxDepth:        maxDepthInt,
+		Directories:     directoriesBool,
+	}
+	if quietPeriodString != NONE {
+		if listOptions.QuietPeriod, err = rotate.ParseDuration(quietPeriodString); err != nil {
+			log.Fatal("Invalid quiet period:", err)
+		}
+	}
+	if settleIntervalString != NONE {
+		if listOptions.SettleInterval, err = rotate.ParseDuration(settleIntervalString); err != nil {
+			log.Fatal("Invalid settle interval:", err)
+		}
+	}
+	manager.SetListOptions(listOptions)
+
+	if timestampPatternString != NONE {
+		timestampPattern, err := rotate.NewTimestampPattern(timestampPatternString)
+		if err != nil {
+			log.Fatal("Invalid timestamp pattern:", err)
+		}
+		manager.SetTimestampPattern(timestampPattern)
+	}
+
+	if includeString != NONE || excludeString != NONE || minSizeString != NONE {
+		var include, exclude []string
+		var minSize int64
+		if includeString != NONE {
+			include = rotate.ParseFilterPatterns(includeString)
+		}
+		if excludeString != NONE {
+			exclude = rotate.ParseFilterPatterns(excludeString)
+		}
+		if minSizeString != NONE {
+			if minSize, err = rotate.ParseSize(minSizeString); err != nil {
+				log.Fatal("Invalid min size:", err)
+			}
+		}
+		filter, err := rotate.NewFilter(include, exclude, minSize)
+		if err != nil {
+			log.Fatal("Invalid filter:", err)
+		}
+		manager.SetFilter(filter)
+	}
+
 	var summary *rotate.Summary
 	summary, err = manager.RotateFiles()
+	if summary != nil {
+		for _, warning := range summary.Warnings {
+			log.Println("Warning:", warning)
+		}
+	}
 
 	if err != nil {
 		switch err {
 		case rotate.ErrEmptyFileList:
+			summary.PrintLeftOut()
 			log.Println("No files to rotate")
 			return
 		case rotate.ErrSingleFile:
+			summary.PrintLeftOut()
 			log.Println("Only one file to rotate, ignoring rotation")
 			return
 		default:
@@ -82,7 +311,7 @@ func HandlerRotate(args map[string]commando.ArgValue, flags map[string]commando.
 		}
 	}
 
-	handleFileDeletion(manager, summary, rotationScheme)
+	handleFileDeletion(manager, summary, rotationScheme, removeEmptyDirsBool)
 
 	summary.Print()
 }
@@ -108,7 +337,8 @@ func initializeProvider(path string) (providers.Provider, error) {
 }
 
 // handleFileDeletion deletes the files from the file provider.
-func handleFileDeletion(manager *rotate.RotationManager, summary *rotate.Summary, scheme *rotate.RotationScheme) {
+// With removeEmptyDirs, the directories the deletion leaves empty are removed too.
+func handleFileDeletion(manager *rotate.RotationManager, summary *rotate.Summary, scheme *rotate.RotationScheme, removeEmptyDirs bool) {
 	if len(summary.ForDelete) == 0 {
 		log.Println("No files eligible for deletion")
 		return
@@ -119,21 +349,53 @@ func handleFileDeletion(manager *rotate.RotationManager, summary *rotate.Summary
 	} else {
 		executeDeletion(manager, summary)
 	}
+
+	if removeEmptyDirs {
+		removeEmptyDirectories(manager, summary, scheme.DryRun)
+	}
 }
 
 // simulateDeletion prints the files that would be deleted in a dry run.
 func simulateDeletion(summary *rotate.Summary) {
 	for _, backup := range summary.ForDelete {
 		log.Println("DRYRUN: simulate file delete...", backup.Path)
+		for _, related := range backup.Related {
+			log.Println("DRYRUN: simulate file delete...", related.Path)
+		}
 	}
 }
 
-// executeDeletion deletes the files from the file provider.
+// executeDeletion deletes the files from the file provider, logging each file as it goes.
 func executeDeletion(manager *rotate.RotationManager, summary *rotate.Summary) {
 	for _, backup := range summary.ForDelete {
-		log.Println("Deleting file...", backup.Path)
-		if err := manager.RemoveFile(backup.Path); err != nil {
+		if err := manager.RemoveFileSet(backup, logDeletion); err != nil {
 			log.Println("Error deleting file:", err)
 		}
 	}
 }
+
+// logDeletion logs a file about to be deleted.
+func logDeletion(file *rotate.File) {
+	log.Println("Deleting file...", file.Path)
+}
+
+// removeEmptyDirectories deletes the directories below the path left empty by the deletion, deepest first,
+// or prints those a dry run would leave empty.
+func removeEmptyDirectories(manager *rotate.RotationManager, summary *rotate.Summary, dryRun bool) {
+	dirs, err := manager.EmptyDirectories(summary.ForDelete)
+	if err != nil {
+		log.Println("Error listing empty directories:", err)
+		return
+	}
+
+	for _, dir := range dirs {
+		if dryRun {
+			log.Println("DRYRUN: simulate empty directory delete...", dir)
+			continue
+		}
+		log.Println("Deleting empty directory...", dir)
+		if err := manager.RemoveEmptyDirectory(dir); err != nil {
+			log.Println("Error deleting directory:", err)
+		}
+	}
+}
@@ -33,6 +33,49 @@ func GetBucketAndKey(fullPath string) (string, string) {
 	return bucket, prefix
 }
 
+// GetBucketAndPrefix returns the bucket and the listing prefix of a full path. (for AWS and Google)
+// The path is a directory with or without its trailing slash, so the prefix ends with a slash and
+// "backups" doesn't match "backups-old/"; a raw prefix is used as given, matching every key that starts with it.
+func GetBucketAndPrefix(fullPath string, raw bool) (string, string) {
+	bucket, prefix := GetBucketAndKey(fullPath)
+	if raw {
+		if _, rest, found := strings.Cut(fullPath, "://"); found {
+			_, prefix, _ = strings.Cut(rest, "/")
+		}
+		return bucket, prefix
+	}
+	return bucket, directoryPrefix(prefix)
+}
+
+// GetAccountContainerAndPrefix returns the account, container and listing prefix of a full path,
+// like GetBucketAndPrefix. (for Azure)
+func GetAccountContainerAndPrefix(fullPath string, raw bool) (string, string, string) {
+	account, container, prefix := GetAccountContainerAndPath(fullPath)
+	if raw {
+		if _, rest, found := strings.Cut(fullPath, "://"); found {
+			if parts := strings.SplitN(rest, "/", 3); len(parts) == 3 {
+				prefix = parts[2]
+			}
+		}
+		return account, container, prefix
+	}
+	return account, container, directoryPrefix(prefix)
+}
+
+// directoryPrefix returns the prefix of the keys within a directory, empty for the root.
+func directoryPrefix(path string) string {
+	if path == "" {
+		return ""
+	}
+	return path + "/"
+}
+
+// IsFolderMarker reports whether an object key or blob name is a folder marker, the empty object
+// some tools create for a folder, rather than a file. (for AWS, Google and Azure)
+func IsFolderMarker(key string) bool {
+	return strings.HasSuffix(key, "/")
+}
+
 // GetAccountContainerAndPath returns the account, container and path from a full path. (for Azure)
 func GetAccountContainerAndPath(fullPath string) (string, string, string) {
 	parts := strings.SplitN(strings.TrimRight(fullPath, "/"), "://", 2)
@@ -69,3 +69,72 @@ func TestGetBucketAndKey(t *testing.T) {
 		}
 	}
 }
+
+func TestGetBucketAndPrefix(t *testing.T) {
+	tests := []struct {
+		input          string
+		raw            bool
+		expectedBucket string
+		expectedPrefix string
+	}{
+		{"s3://my-bucket/backups", false, "my-bucket", "backups/"},
+		{"s3://my-bucket/backups/", false, "my-bucket", "backups/"},
+		{"s3://my-bucket/backups//", false, "my-bucket", "backups/"},
+		{"gs://my-bucket/backups/daily", false, "my-bucket", "backups/daily/"},
+		{"s3://my-bucket/", false, "my-bucket", ""},
+		{"s3://my-bucket", false, "my-bucket", ""},
+		{"s3://my-bucket/backups", true, "my-bucket", "backups"},
+		{"s3://my-bucket/backups/db-2024", true, "my-bucket", "backups/db-2024"},
+		{"s3://my-bucket/backups/", true, "my-bucket", "backups/"},
+		{"s3://my-bucket", true, "my-bucket", ""},
+	}
+
+	for _, test := range tests {
+		bucket, prefix := utils.GetBucketAndPrefix(test.input, test.raw)
+		if bucket != test.expectedBucket || prefix != test.expectedPrefix {
+			t.Errorf("Input: %s (raw %t) - Expected: (%s, %s), Got: (%s, %s)",
+				test.input, test.raw, test.expectedBucket, test.expectedPrefix, bucket, prefix)
+		}
+	}
+}
+
+func TestGetAccountContainerAndPrefix(t *testing.T) {
+	tests := []struct {
+		input             string
+		raw               bool
+		expectedAccount   string
+		expectedContainer string
+		expectedPrefix    string
+	}{
+		{"blob://account/container/backups", false, "account", "container", "backups/"},
+		{"blob://account/container/backups/", false, "account", "container", "backups/"},
+		{"blob://account/container/", false, "account", "container", ""},
+		{"blob://account/container", false, "account", "container", ""},
+		{"blob://account/container/backups", true, "account", "container", "backups"},
+		{"blob://account/container/backups/", true, "account", "container", "backups/"},
+		{"blob://account/container", true, "account", "container", ""},
+		{"blob://account", true, "", "", ""},
+	}
+
+	for _, test := range tests {
+		account, container, prefix := utils.GetAccountContainerAndPrefix(test.input, test.raw)
+		if account != test.expectedAccount || container != test.expectedContainer || prefix != test.expectedPrefix {
+			t.Errorf("Input: %s (raw %t) - Expected: (%s, %s, %s), Got: (%s, %s, %s)",
+				test.input, test.raw, test.expectedAccount, test.expectedContainer, test.expectedPrefix, account, container, prefix)
+		}
+	}
+}
+
+func TestIsFolderMarker(t *testing.T) {
+	for key, expected := range map[string]bool{
+		"backups/":          true,
+		"backups/2024/":     true,
+		"backups/db.sql.gz": false,
+		"backups":           false,
+		"":                  false,
+	} {
+		if marker := utils.IsFolderMarker(key); marker != expected {
+			t.Errorf("Input: %q - Expected: %t, Got: %t", key, expected, marker)
+		}
+	}
+}
@@ -18,12 +18,16 @@ package aws
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
-	"github.com/golang-module/carbon"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/smithy-go"
 	"github.com/raniellyferreira/rotate-files/internal/environment"
 	"github.com/raniellyferreira/rotate-files/internal/utils"
 	"github.com/raniellyferreira/rotate-files/pkg/providers"
@@ -75,31 +79,180 @@ func (a *AWSProvider) Delete(path string) error {
 	return err
 }
 
-// ListFiles retrieves and lists all files within an S3 bucket with the given full path.
+// ListFiles lists the files under the full path with the default options.
 func (a *AWSProvider) ListFiles(fullPath string) ([]*providers.FileInfo, error) {
+	return a.ListFilesWithOptions(fullPath, providers.ListOptions{})
+}
+
+// ListFilesWithOptions retrieves and lists all files within an S3 bucket with the given full path, a directory unless the options ask for a raw prefix.
+// S3 has no creation time; metadata and tag sources issue one extra request per object.
+// Multipart uploads not completed yet are listed as in progress, so they are reported but never rotated.
+// When they can't be listed, the files are returned along with providers.ErrUploadsNotListed.
+func (a *AWSProvider) ListFilesWithOptions(fullPath string, opts providers.ListOptions) ([]*providers.FileInfo, error) {
+	bucket, prefix := utils.GetBucketAndPrefix(fullPath, opts.RawPrefix)
+	if opts.Directories {
+		return a.listDirectories(bucket, prefix, opts)
+	}
+
+	files, err := a.listFiles(bucket, prefix, opts.MaxDepth, opts)
+	if err != nil {
+		return nil, err
+	}
+	uploads, err := a.listUploads(bucket, prefix, opts.MaxDepth, opts)
+	if err != nil && !errors.Is(err, providers.ErrUploadsNotListed) {
+		return nil, err
+	}
+	return append(files, uploads...), err
+}
+
+// listUploads lists the multipart uploads in progress under the prefix, up to depth levels deep when the
+// depth is positive, timestamped with their start. Without the s3:ListBucketMultipartUploads permission
+// it returns providers.ErrUploadsNotListed.
+func (a *AWSProvider) listUploads(bucket, prefix string, depth int, opts providers.ListOptions) ([]*providers.FileInfo, error) {
+	var uploads []*providers.FileInfo
+	paginator := s3.NewListMultipartUploadsPaginator(a.client, &s3.ListMultipartUploadsInput{
+		Bucket: aws.String(bucket),
+		Prefix: aws.String(prefix),
+	})
+	for paginator.HasMorePages() {
+		resp, err := paginator.NextPage(context.Background())
+		var apiErr smithy.APIError
+		if errors.As(err, &apiErr) && apiErr.ErrorCode() == "AccessDenied" {
+			return nil, fmt.Errorf("%w: %w", providers.ErrUploadsNotListed, err)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		for _, upload := range resp.Uploads {
+			key := aws.ToString(upload.Key)
+			if depth > 0 && strings.Count(key[len(prefix):], "/") >= depth {
+				continue
+			}
+			timestamp, source := opts.TimestampSource.Resolve(providers.Timestamps{Modified: aws.ToTime(upload.Initiated)})
+			uploads = append(uploads, &providers.FileInfo{
+				Path:            fmt.Sprintf("s3://%s/%s", bucket, key),
+				Timestamp:       timestamp,
+				TimestampSource: source,
+				InProgress:      true,
+			})
+		}
+	}
+	return uploads, nil
+}
+
+// listDirectories lists each common prefix under the prefix as one entry, with the size and newest
+// modification time of its objects. Once the uploads can't be listed, the remaining prefixes are listed
+// without them, and the directories are returned along with the error.
+func (a *AWSProvider) listDirectories(bucket, prefix string, opts providers.ListOptions) ([]*providers.FileInfo, error) {
+	var dirs []*providers.FileInfo
+	var uploadsErr error
+	paginator := s3.NewListObjectsV2Paginator(a.client, &s3.ListObjectsV2Input{
+		Bucket:    aws.String(bucket),
+		Prefix:    aws.String(prefix),
+		Delimiter: aws.String("/"),
+	})
+	for paginator.HasMorePages() {
+		resp, err := paginator.NextPage(context.Background())
+		if err != nil {
+			return nil, err
+		}
+
+		for _, commonPrefix := range resp.CommonPrefixes {
+			files, err := a.listFiles(bucket, aws.ToString(commonPrefix.Prefix), 0, providers.ListOptions{})
+			if err != nil {
+				return nil, err
+			}
+			if uploadsErr == nil {
+				uploads, err := a.listUploads(bucket, aws.ToString(commonPrefix.Prefix), 0, providers.ListOptions{})
+				if err != nil && !errors.Is(err, providers.ErrUploadsNotListed) {
+					return nil, err
+				}
+				files, uploadsErr = append(files, uploads...), err
+			}
+			path := fmt.Sprintf("s3://%s/%s", bucket, strings.TrimSuffix(aws.ToString(commonPrefix.Prefix), "/"))
+			dirs = append(dirs, providers.DirectoryInfo(path, files, time.Time{}, opts))
+		}
+	}
+	return dirs, uploadsErr
+}
+
+// DeleteDirectory removes every object under a directory listed in directory mode, folder markers included.
+// It refuses the bucket root.
+func (a *AWSProvider) DeleteDirectory(fullPath string) error {
+	bucket, prefix := utils.GetBucketAndPrefix(fullPath, false)
+	if prefix == "" {
+		return fmt.Errorf("refusing to delete the root of bucket %s", bucket)
+	}
+
+	paginator := s3.NewListObjectsV2Paginator(a.client, &s3.ListObjectsV2Input{
+		Bucket: aws.String(bucket),
+		Prefix: aws.String(prefix),
+	})
+	for paginator.HasMorePages() {
+		resp, err := paginator.NextPage(context.Background())
+		if err != nil {
+			return err
+		}
+
+		for _, obj := range resp.Contents {
+			if _, err := a.client.DeleteObject(context.Background(), &s3.DeleteObjectInput{
+				Bucket: aws.String(bucket),
+				Key:    obj.Key,
+			}); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// listFiles lists the objects under the prefix, up to depth levels deep when the depth is positive: listing
+// with a delimiter returns the objects of the level and its subdirectories as common prefixes, listed in turn.
+func (a *AWSProvider) listFiles(bucket, prefix string, depth int, opts providers.ListOptions) ([]*providers.FileInfo, error) {
 	var continuationToken *string
 	var files []*providers.FileInfo
 
-	bucket, path := utils.GetBucketAndKey(fullPath)
+	input := &s3.ListObjectsV2Input{
+		Bucket: aws.String(bucket),
+		Prefix: aws.String(prefix),
+	}
+	if depth > 0 {
+		input.Delimiter = aws.String("/")
+	}
 
 	for {
-		resp, err := a.client.ListObjectsV2(context.Background(), &s3.ListObjectsV2Input{
-			Bucket:            aws.String(bucket),
-			Prefix:            aws.String(path),
-			ContinuationToken: continuationToken,
-		})
+		input.ContinuationToken = continuationToken
+		resp, err := a.client.ListObjectsV2(context.Background(), input)
 		if err != nil {
 			return nil, err
 		}
 
 		for _, obj := range resp.Contents {
+			if utils.IsFolderMarker(aws.ToString(obj.Key)) {
+				continue
+			}
+
+			timestamps := a.objectTimestamps(bucket, obj, opts.TimestampSource)
+			timestamp, source := opts.TimestampSource.Resolve(timestamps)
 			files = append(files, &providers.FileInfo{
-				Path:      fmt.Sprintf("s3://%s/%s", bucket, aws.ToString(obj.Key)),
-				Size:      aws.ToInt64(obj.Size),
-				Timestamp: carbon.FromStdTime(aws.ToTime(obj.LastModified)),
+				Path:            fmt.Sprintf("s3://%s/%s", bucket, aws.ToString(obj.Key)),
+				Size:            aws.ToInt64(obj.Size),
+				Timestamp:       timestamp,
+				TimestampSource: source,
 			})
 		}
 
+		if depth > 1 {
+			for _, commonPrefix := range resp.CommonPrefixes {
+				subFiles, err := a.listFiles(bucket, aws.ToString(commonPrefix.Prefix), depth-1, opts)
+				if err != nil {
+					return nil, err
+				}
+				files = append(files, subFiles...)
+			}
+		}
+
 		if aws.ToBool(resp.IsTruncated) {
 			continuationToken = resp.NextContinuationToken
 		} else {
@@ -109,3 +262,34 @@ func (a *AWSProvider) ListFiles(fullPath string) ([]*providers.FileInfo, error)
 
 	return files, nil
 }
+
+// objectTimestamps collects the timestamp candidates of an object, fetching metadata or tags only when the source needs them.
+// A failed lookup leaves them out, so the object falls back to its last modified time instead of failing the listing.
+func (a *AWSProvider) objectTimestamps(bucket string, obj types.Object, source providers.TimestampSource) providers.Timestamps {
+	timestamps := providers.Timestamps{Modified: aws.ToTime(obj.LastModified)}
+
+	if source.NeedsMetadata() {
+		head, err := a.client.HeadObject(context.Background(), &s3.HeadObjectInput{
+			Bucket: aws.String(bucket),
+			Key:    obj.Key,
+		})
+		if err == nil {
+			timestamps.Metadata = head.Metadata
+		}
+	}
+
+	if source.NeedsTags() {
+		tagging, err := a.client.GetObjectTagging(context.Background(), &s3.GetObjectTaggingInput{
+			Bucket: aws.String(bucket),
+			Key:    obj.Key,
+		})
+		if err == nil {
+			timestamps.Tags = make(map[string]string, len(tagging.TagSet))
+			for _, tag := range tagging.TagSet {
+				timestamps.Tags[aws.ToString(tag.Key)] = aws.ToString(tag.Value)
+			}
+		}
+	}
+
+	return timestamps
+}
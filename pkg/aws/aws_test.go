@@ -15,3 +15,239 @@ limitations under the License.
 */
 
 package aws_test
+
+import (
+	"encoding/xml"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/raniellyferreira/rotate-files/pkg/aws"
+	"github.com/raniellyferreira/rotate-files/pkg/providers"
+	"github.com/stretchr/testify/assert"
+)
+
+// deniedUploads makes fakeS3 deny listing the multipart uploads, like a policy without s3:ListBucketMultipartUploads.
+var deniedUploads = []string{"<denied>"}
+
+// fakeS3 serves ListObjectsV2 for a bucket holding the given keys, matching them by prefix like S3 does,
+// and rolling up the keys below the delimiter into common prefixes. It serves ListMultipartUploads for
+// the keys of the uploads in progress, or denies it when they are deniedUploads.
+func fakeS3(t *testing.T, bucket string, keys []string, uploads ...string) *httptest.Server {
+	type object struct {
+		Key          string
+		Size         int64
+		LastModified string
+	}
+	type commonPrefix struct {
+		Prefix string
+	}
+	type listBucketResult struct {
+		XMLName        xml.Name `xml:"ListBucketResult"`
+		Name           string
+		Prefix         string
+		IsTruncated    bool
+		Contents       []object
+		CommonPrefixes []commonPrefix
+	}
+	type upload struct {
+		Key       string
+		UploadId  string
+		Initiated string
+	}
+	type listMultipartUploadsResult struct {
+		XMLName     xml.Name `xml:"ListMultipartUploadsResult"`
+		Bucket      string
+		Prefix      string
+		IsTruncated bool
+		Upload      []upload
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		prefix, delimiter := r.URL.Query().Get("prefix"), r.URL.Query().Get("delimiter")
+		if r.URL.Path == "/"+bucket && r.URL.Query().Has("uploads") {
+			if slices.Equal(uploads, deniedUploads) {
+				w.Header().Set("Content-Type", "application/xml")
+				w.WriteHeader(http.StatusForbidden)
+				_, err := w.Write([]byte(`<Error><Code>AccessDenied</Code><Message>Access Denied</Message></Error>`))
+				assert.NoError(t, err)
+				return
+			}
+			result := listMultipartUploadsResult{Bucket: bucket, Prefix: prefix}
+			for _, key := range uploads {
+				if strings.HasPrefix(key, prefix) {
+					result.Upload = append(result.Upload, upload{Key: key, UploadId: "upload-" + key, Initiated: "2024-06-15T11:00:00.000Z"})
+				}
+			}
+			w.Header().Set("Content-Type", "application/xml")
+			assert.NoError(t, xml.NewEncoder(w).Encode(result))
+			return
+		}
+		if r.URL.Path != "/"+bucket || r.URL.Query().Get("list-type") != "2" {
+			http.NotFound(w, r)
+			return
+		}
+		result := listBucketResult{Name: bucket, Prefix: prefix}
+		for _, key := range keys {
+			if !strings.HasPrefix(key, prefix) {
+				continue
+			}
+			if i := strings.Index(key[len(prefix):], delimiter); delimiter != "" && i >= 0 {
+				common := commonPrefix{Prefix: key[:len(prefix)+i+1]}
+				if !slices.Contains(result.CommonPrefixes, common) {
+					result.CommonPrefixes = append(result.CommonPrefixes, common)
+				}
+				continue
+			}
+			result.Contents = append(result.Contents, object{Key: key, Size: 10, LastModified: "2024-06-15T10:00:00.000Z"})
+		}
+		w.Header().Set("Content-Type", "application/xml")
+		assert.NoError(t, xml.NewEncoder(w).Encode(result))
+	}))
+	t.Cleanup(server.Close)
+
+	t.Setenv("AWS_ENDPOINT_OVERRIDE", server.URL)
+	t.Setenv("AWS_ACCESS_KEY_ID", "test")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "test")
+	return server
+}
+
+func TestListFilesPrefixBoundary(t *testing.T) {
+	fakeS3(t, "bucket", []string{
+		"backups/",
+		"backups/db-1.sql",
+		"backups/db-2.sql",
+		"backups-old/db-0.sql",
+		"backups2/db-0.sql",
+	})
+
+	provider, err := aws.NewAWSProvider()
+	assert.NoError(t, err)
+
+	for _, path := range []string{"s3://bucket/backups", "s3://bucket/backups/"} {
+		files, err := provider.ListFiles(path)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"s3://bucket/backups/db-1.sql", "s3://bucket/backups/db-2.sql"}, paths(files), path)
+	}
+
+	files, err := provider.ListFilesWithOptions("s3://bucket/backups", providers.ListOptions{RawPrefix: true})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		"s3://bucket/backups/db-1.sql",
+		"s3://bucket/backups/db-2.sql",
+		"s3://bucket/backups-old/db-0.sql",
+		"s3://bucket/backups2/db-0.sql",
+	}, paths(files))
+}
+
+func TestListFilesMaxDepth(t *testing.T) {
+	fakeS3(t, "bucket", []string{
+		"backups/db-1.sql",
+		"backups/daily/db-2.sql",
+		"backups/daily/archive/db-3.sql",
+		"backups-old/db-0.sql",
+	})
+
+	provider, err := aws.NewAWSProvider()
+	assert.NoError(t, err)
+
+	for maxDepth, expected := range map[int][]string{
+		1: {"s3://bucket/backups/db-1.sql"},
+		2: {"s3://bucket/backups/db-1.sql", "s3://bucket/backups/daily/db-2.sql"},
+		0: {"s3://bucket/backups/daily/archive/db-3.sql", "s3://bucket/backups/daily/db-2.sql", "s3://bucket/backups/db-1.sql"},
+	} {
+		files, err := provider.ListFilesWithOptions("s3://bucket/backups", providers.ListOptions{MaxDepth: maxDepth})
+		assert.NoError(t, err)
+		assert.ElementsMatch(t, expected, paths(files), maxDepth)
+	}
+}
+
+func TestListFilesDirectories(t *testing.T) {
+	fakeS3(t, "bucket", []string{
+		"backups/2024-10-01T00:00/base.tar",
+		"backups/2024-10-01T00:00/wal/0001",
+		"backups/2024-10-02T00:00/base.tar",
+		"backups/loose.tar",
+	})
+
+	provider, err := aws.NewAWSProvider()
+	assert.NoError(t, err)
+
+	files, err := provider.ListFilesWithOptions("s3://bucket/backups/", providers.ListOptions{Directories: true})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"s3://bucket/backups/2024-10-01T00:00", "s3://bucket/backups/2024-10-02T00:00"}, paths(files))
+	assert.True(t, files[0].Directory)
+	assert.Equal(t, int64(20), files[0].Size)
+	assert.Equal(t, int64(10), files[1].Size)
+}
+
+func TestListFilesUploadsInProgress(t *testing.T) {
+	fakeS3(t, "bucket", []string{
+		"backups/db-1.sql",
+		"backups/2024-10-01T00:00/base.tar",
+	}, "backups/db-2.sql", "backups/2024-10-01T00:00/wal.tar", "backups-old/db-3.sql")
+
+	provider, err := aws.NewAWSProvider()
+	assert.NoError(t, err)
+
+	files, err := provider.ListFilesWithOptions("s3://bucket/backups", providers.ListOptions{MaxDepth: 1})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"s3://bucket/backups/db-1.sql", "s3://bucket/backups/db-2.sql"}, paths(files))
+	assert.False(t, files[0].InProgress)
+	assert.True(t, files[1].InProgress)
+	assert.Equal(t, "2024-06-15 11:00:00", files[1].Timestamp.SetTimezone("UTC").ToDateTimeString())
+
+	files, err = provider.ListFilesWithOptions("s3://bucket/backups", providers.ListOptions{Directories: true})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"s3://bucket/backups/2024-10-01T00:00"}, paths(files))
+	assert.True(t, files[0].InProgress)
+}
+
+func TestListFilesUploadsDenied(t *testing.T) {
+	fakeS3(t, "bucket", []string{
+		"backups/db-1.sql",
+		"backups/2024-10-01T00:00/base.tar",
+	}, deniedUploads...)
+
+	provider, err := aws.NewAWSProvider()
+	assert.NoError(t, err)
+
+	// Without the permission to list the uploads, the objects are still listed, along with the error
+	files, err := provider.ListFilesWithOptions("s3://bucket/backups", providers.ListOptions{MaxDepth: 1})
+	assert.ErrorIs(t, err, providers.ErrUploadsNotListed)
+	assert.Equal(t, []string{"s3://bucket/backups/db-1.sql"}, paths(files))
+
+	files, err = provider.ListFilesWithOptions("s3://bucket/backups", providers.ListOptions{Directories: true})
+	assert.ErrorIs(t, err, providers.ErrUploadsNotListed)
+	assert.Equal(t, []string{"s3://bucket/backups/2024-10-01T00:00"}, paths(files))
+	assert.False(t, files[0].InProgress)
+}
+
+func TestListFilesTimestampLookupFailure(t *testing.T) {
+	fakeS3(t, "bucket", []string{"backups/db-1.sql", "backups/db-2.sql"})
+
+	provider, err := aws.NewAWSProvider()
+	assert.NoError(t, err)
+
+	// The fake serves no HeadObject nor GetObjectTagging, so every lookup fails
+	for _, value := range []string{"metadata:backup-date", "tag:backup-date"} {
+		source, err := providers.ParseTimestampSource(value)
+		assert.NoError(t, err)
+		files, err := provider.ListFilesWithOptions("s3://bucket/backups", providers.ListOptions{TimestampSource: source})
+		assert.NoError(t, err, value)
+		assert.Equal(t, []string{"s3://bucket/backups/db-1.sql", "s3://bucket/backups/db-2.sql"}, paths(files))
+		assert.Equal(t, providers.SourceModified, files[0].TimestampSource)
+		assert.Equal(t, "2024-06-15 10:00:00", files[0].Timestamp.SetTimezone("UTC").ToDateTimeString())
+	}
+}
+
+// paths returns the paths of the listed files.
+func paths(files []*providers.FileInfo) []string {
+	var result []string
+	for _, file := range files {
+		result = append(result, file.Path)
+	}
+	return result
+}
@@ -19,11 +19,13 @@ package azure
 import (
 	"context"
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
 	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/container"
 	"github.com/aws/aws-sdk-go-v2/aws"
-	"github.com/golang-module/carbon"
 	"github.com/raniellyferreira/rotate-files/internal/environment"
 	"github.com/raniellyferreira/rotate-files/internal/utils"
 	"github.com/raniellyferreira/rotate-files/pkg/providers"
@@ -73,10 +75,36 @@ func (az *AzureProvider) Delete(fullPath string) error {
 	return err
 }
 
-// ListFiles retrieves and lists all blobs within an Azure container with the given full path.
+// defaultTimestampSource is the timestamp source of blobs when none is selected: their creation time.
+var defaultTimestampSource = providers.TimestampSource{Kind: providers.SourceCreated}
+
+// ListFiles lists the files under the full path with the default options.
 func (az *AzureProvider) ListFiles(fullPath string) ([]*providers.FileInfo, error) {
-	account, container, prefix := utils.GetAccountContainerAndPath(fullPath)
-	pager := az.client.NewListBlobsFlatPager(container, &azblob.ListBlobsFlatOptions{Prefix: &prefix})
+	return az.ListFilesWithOptions(fullPath, providers.ListOptions{})
+}
+
+// ListFilesWithOptions retrieves and lists all blobs within an Azure container with the given full path, a directory
+// unless the options ask for a raw prefix.
+func (az *AzureProvider) ListFilesWithOptions(fullPath string, opts providers.ListOptions) ([]*providers.FileInfo, error) {
+	account, containerName, prefix := utils.GetAccountContainerAndPrefix(fullPath, opts.RawPrefix)
+	include := container.ListBlobsInclude{
+		Metadata: opts.TimestampSource.NeedsMetadata(),
+		Tags:     opts.TimestampSource.NeedsTags(),
+	}
+
+	if opts.Directories {
+		return az.listDirectories(account, containerName, prefix, opts)
+	}
+
+	if opts.MaxDepth > 0 {
+		client := az.client.ServiceClient().NewContainerClient(containerName)
+		return listHierarchy(client, account, containerName, prefix, opts.MaxDepth, include, opts)
+	}
+
+	pager := az.client.NewListBlobsFlatPager(containerName, &azblob.ListBlobsFlatOptions{
+		Prefix:  &prefix,
+		Include: include,
+	})
 
 	var files []*providers.FileInfo
 	for pager.More() {
@@ -84,15 +112,132 @@ func (az *AzureProvider) ListFiles(fullPath string) ([]*providers.FileInfo, erro
 		if err != nil {
 			return nil, err
 		}
+		files = append(files, blobFiles(account, containerName, resp.Segment.BlobItems, opts)...)
+	}
+
+	return files, nil
+}
+
+// listDirectories lists each blob prefix under the prefix as one entry, with the size and newest
+// modification time of its blobs.
+func (az *AzureProvider) listDirectories(account, containerName, prefix string, opts providers.ListOptions) ([]*providers.FileInfo, error) {
+	client := az.client.ServiceClient().NewContainerClient(containerName)
+	pager := client.NewListBlobsHierarchyPager("/", &container.ListBlobsHierarchyOptions{Prefix: &prefix})
+
+	var dirs []*providers.FileInfo
+	for pager.More() {
+		resp, err := pager.NextPage(context.Background())
+		if err != nil {
+			return nil, err
+		}
+
+		for _, blobPrefix := range resp.Segment.BlobPrefixes {
+			dirPrefix := aws.ToString(blobPrefix.Name)
+			files, err := az.ListFilesWithOptions(fmt.Sprintf("blob://%s/%s/%s", account, containerName, dirPrefix), providers.ListOptions{RawPrefix: true})
+			if err != nil {
+				return nil, err
+			}
+			path := fmt.Sprintf("blob://%s/%s/%s", account, containerName, strings.TrimSuffix(dirPrefix, "/"))
+			dirs = append(dirs, providers.DirectoryInfo(path, files, time.Time{}, opts))
+		}
+	}
+	return dirs, nil
+}
+
+// DeleteDirectory removes every blob under a directory listed in directory mode, folder markers included.
+// It refuses the container root.
+func (az *AzureProvider) DeleteDirectory(fullPath string) error {
+	_, containerName, prefix := utils.GetAccountContainerAndPrefix(fullPath, false)
+	if prefix == "" {
+		return fmt.Errorf("refusing to delete the root of container %s", containerName)
+	}
+
+	pager := az.client.NewListBlobsFlatPager(containerName, &azblob.ListBlobsFlatOptions{Prefix: &prefix})
+	for pager.More() {
+		resp, err := pager.NextPage(context.Background())
+		if err != nil {
+			return err
+		}
 
 		for _, blob := range resp.Segment.BlobItems {
-			files = append(files, &providers.FileInfo{
-				Path:      fmt.Sprintf("blob://%s/%s/%s", account, container, aws.ToString(blob.Name)),
-				Size:      aws.ToInt64(blob.Properties.ContentLength),
-				Timestamp: carbon.FromStdTime(aws.ToTime(blob.Properties.CreationTime)),
-			})
+			if _, err := az.client.DeleteBlob(context.Background(), containerName, aws.ToString(blob.Name), nil); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// listHierarchy lists the blobs under the prefix, up to depth levels deep: listing with a delimiter returns
+// the blobs of the level and its subdirectories as blob prefixes, listed in turn.
+func listHierarchy(client *container.Client, account, containerName, prefix string, depth int,
+	include container.ListBlobsInclude, opts providers.ListOptions) ([]*providers.FileInfo, error) {
+	pager := client.NewListBlobsHierarchyPager("/", &container.ListBlobsHierarchyOptions{
+		Prefix:  &prefix,
+		Include: include,
+	})
+
+	var files []*providers.FileInfo
+	for pager.More() {
+		resp, err := pager.NextPage(context.Background())
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, blobFiles(account, containerName, resp.Segment.BlobItems, opts)...)
+
+		if depth > 1 {
+			for _, blobPrefix := range resp.Segment.BlobPrefixes {
+				subFiles, err := listHierarchy(client, account, containerName, aws.ToString(blobPrefix.Name), depth-1, include, opts)
+				if err != nil {
+					return nil, err
+				}
+				files = append(files, subFiles...)
+			}
 		}
 	}
 
 	return files, nil
 }
+
+// blobFiles returns the files of the listed blobs.
+func blobFiles(account, containerName string, blobs []*container.BlobItem, opts providers.ListOptions) []*providers.FileInfo {
+	var files []*providers.FileInfo
+	for _, blob := range blobs {
+		if utils.IsFolderMarker(aws.ToString(blob.Name)) {
+			continue
+		}
+
+		timestamp, source := opts.TimestampSource.OrDefault(defaultTimestampSource).Resolve(blobTimestamps(blob))
+		files = append(files, &providers.FileInfo{
+			Path:            fmt.Sprintf("blob://%s/%s/%s", account, containerName, aws.ToString(blob.Name)),
+			Size:            aws.ToInt64(blob.Properties.ContentLength),
+			Timestamp:       timestamp,
+			TimestampSource: source,
+		})
+	}
+	return files
+}
+
+// blobTimestamps collects the timestamp candidates of a listed blob.
+func blobTimestamps(blob *container.BlobItem) providers.Timestamps {
+	timestamps := providers.Timestamps{
+		Modified: aws.ToTime(blob.Properties.LastModified),
+		Created:  aws.ToTime(blob.Properties.CreationTime),
+	}
+
+	if blob.Metadata != nil {
+		timestamps.Metadata = make(map[string]string, len(blob.Metadata))
+		for key, value := range blob.Metadata {
+			timestamps.Metadata[key] = aws.ToString(value)
+		}
+	}
+
+	if blob.BlobTags != nil {
+		timestamps.Tags = make(map[string]string, len(blob.BlobTags.BlobTagSet))
+		for _, tag := range blob.BlobTags.BlobTagSet {
+			timestamps.Tags[aws.ToString(tag.Key)] = aws.ToString(tag.Value)
+		}
+	}
+
+	return timestamps
+}
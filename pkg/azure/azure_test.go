@@ -0,0 +1,171 @@
+/*
+Copyright The Rotate Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package azure_test
+
+import (
+	"encoding/base64"
+	"encoding/xml"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/raniellyferreira/rotate-files/pkg/azure"
+	"github.com/raniellyferreira/rotate-files/pkg/providers"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeBlobStorage serves the blob listing of a container holding the given names, matching them by prefix like
+// Azure does, and rolling up the names below the delimiter into blob prefixes.
+func fakeBlobStorage(t *testing.T, account, container string, names []string) {
+	type properties struct {
+		CreationTime  string `xml:"Creation-Time"`
+		LastModified  string `xml:"Last-Modified"`
+		ContentLength int64  `xml:"Content-Length"`
+	}
+	type blob struct {
+		Name       string
+		Properties properties
+	}
+	type blobPrefix struct {
+		Name string
+	}
+	type enumerationResults struct {
+		XMLName       xml.Name `xml:"EnumerationResults"`
+		ContainerName string   `xml:"ContainerName,attr"`
+		Prefix        string
+		Blobs         []blob       `xml:"Blobs>Blob"`
+		BlobPrefixes  []blobPrefix `xml:"Blobs>BlobPrefix"`
+		NextMarker    string
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/"+account+"/"+container || r.URL.Query().Get("comp") != "list" {
+			http.NotFound(w, r)
+			return
+		}
+		prefix, delimiter := r.URL.Query().Get("prefix"), r.URL.Query().Get("delimiter")
+		result := enumerationResults{ContainerName: container, Prefix: prefix}
+		for _, name := range names {
+			if !strings.HasPrefix(name, prefix) {
+				continue
+			}
+			if i := strings.Index(name[len(prefix):], delimiter); delimiter != "" && i >= 0 {
+				common := blobPrefix{Name: name[:len(prefix)+i+1]}
+				if !slices.Contains(result.BlobPrefixes, common) {
+					result.BlobPrefixes = append(result.BlobPrefixes, common)
+				}
+				continue
+			}
+			result.Blobs = append(result.Blobs, blob{Name: name, Properties: properties{
+				CreationTime: "Fri, 14 Jun 2024 08:00:00 GMT", LastModified: "Sat, 15 Jun 2024 10:00:00 GMT", ContentLength: 10,
+			}})
+		}
+		w.Header().Set("Content-Type", "application/xml")
+		assert.NoError(t, xml.NewEncoder(w).Encode(result))
+	}))
+	t.Cleanup(server.Close)
+
+	t.Setenv("AZURE_STORAGE_CONNECTION_STRING", "DefaultEndpointsProtocol=http;AccountName="+account+
+		";AccountKey="+base64.StdEncoding.EncodeToString([]byte("key"))+";BlobEndpoint="+server.URL+"/"+account+";")
+}
+
+func TestListFilesPrefixBoundary(t *testing.T) {
+	fakeBlobStorage(t, "account", "container", []string{
+		"backups/",
+		"backups/db-1.sql",
+		"backups/db-2.sql",
+		"backups-old/db-0.sql",
+		"backups2/db-0.sql",
+	})
+
+	provider, err := azure.NewAzureProvider("blob://account/container/backups")
+	assert.NoError(t, err)
+
+	for _, path := range []string{"blob://account/container/backups", "blob://account/container/backups/"} {
+		files, err := provider.ListFiles(path)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{
+			"blob://account/container/backups/db-1.sql",
+			"blob://account/container/backups/db-2.sql",
+		}, paths(files), path)
+	}
+
+	files, err := provider.ListFilesWithOptions("blob://account/container/backups", providers.ListOptions{RawPrefix: true})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		"blob://account/container/backups/db-1.sql",
+		"blob://account/container/backups/db-2.sql",
+		"blob://account/container/backups-old/db-0.sql",
+		"blob://account/container/backups2/db-0.sql",
+	}, paths(files))
+}
+
+func TestListFilesMaxDepth(t *testing.T) {
+	fakeBlobStorage(t, "account", "container", []string{
+		"backups/db-1.sql",
+		"backups/daily/db-2.sql",
+		"backups/daily/archive/db-3.sql",
+		"backups-old/db-0.sql",
+	})
+
+	provider, err := azure.NewAzureProvider("blob://account/container/backups")
+	assert.NoError(t, err)
+
+	for maxDepth, expected := range map[int][]string{
+		1: {"blob://account/container/backups/db-1.sql"},
+		2: {"blob://account/container/backups/db-1.sql", "blob://account/container/backups/daily/db-2.sql"},
+		0: {
+			"blob://account/container/backups/daily/archive/db-3.sql",
+			"blob://account/container/backups/daily/db-2.sql",
+			"blob://account/container/backups/db-1.sql",
+		},
+	} {
+		files, err := provider.ListFilesWithOptions("blob://account/container/backups", providers.ListOptions{MaxDepth: maxDepth})
+		assert.NoError(t, err)
+		assert.ElementsMatch(t, expected, paths(files), maxDepth)
+	}
+}
+
+func TestListFilesDefaultTimestampSource(t *testing.T) {
+	fakeBlobStorage(t, "account", "container", []string{"backups/db-1.sql"})
+
+	provider, err := azure.NewAzureProvider("blob://account/container/backups")
+	assert.NoError(t, err)
+
+	// Blobs were rotated by their creation time before the source was selectable, and still are by default
+	files, err := provider.ListFiles("blob://account/container/backups")
+	assert.NoError(t, err)
+	assert.Equal(t, "2024-06-14 08:00:00", files[0].Timestamp.SetTimezone("UTC").ToDateTimeString())
+	assert.Equal(t, providers.SourceCreated, files[0].TimestampSource)
+
+	files, err = provider.ListFilesWithOptions("blob://account/container/backups", providers.ListOptions{
+		TimestampSource: providers.TimestampSource{Kind: providers.SourceModified},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "2024-06-15 10:00:00", files[0].Timestamp.SetTimezone("UTC").ToDateTimeString())
+}
+
+// paths returns the paths of the listed files.
+func paths(files []*providers.FileInfo) []string {
+	var result []string
+	for _, file := range files {
+		result = append(result, file.Path)
+	}
+	return result
+}
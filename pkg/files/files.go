@@ -17,10 +17,14 @@ limitations under the License.
 package files
 
 import (
+	"fmt"
+	"io/fs"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
+	"time"
 
-	"github.com/golang-module/carbon"
 	"github.com/raniellyferreira/rotate-files/pkg/providers"
 )
 
@@ -36,27 +40,223 @@ func (l *LocalProvider) Delete(fullPath string) error {
 	return os.Remove(fullPath)
 }
 
-// ListFiles traverses the local directory specified by fullPath and returns a list of files.
+// DeleteDirectory removes a directory listed in directory mode with everything below it. It refuses
+// anything but a real directory, such as a symbolic link put in its place, and the filesystem root.
+func (l *LocalProvider) DeleteDirectory(fullPath string) error {
+	info, err := os.Lstat(fullPath)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("refusing to delete %s: not a directory", fullPath)
+	}
+	if clean := filepath.Clean(fullPath); clean == "." || clean == filepath.Dir(clean) {
+		return fmt.Errorf("refusing to delete %s", fullPath)
+	}
+	return os.RemoveAll(fullPath)
+}
+
+// EmptyDirectories returns the directories strictly below the root holding nothing but the deleted paths and
+// other such directories, deepest first. The deleted paths may already be gone, so it serves both before
+// deletion, for a dry run, and after it. Only the parents of the deleted paths are considered, and the root never is.
+func (l *LocalProvider) EmptyDirectories(root string, deleted []string) ([]string, error) {
+	root = filepath.Clean(root)
+	gone := make(map[string]bool)
+	var candidates []string
+	for _, path := range deleted {
+		gone[filepath.Clean(path)] = true
+		for dir := filepath.Dir(filepath.Clean(path)); isBelow(root, dir) && !gone[dir+string(filepath.Separator)]; dir = filepath.Dir(dir) {
+			// The trailing separator marks the directory as a candidate without confusing it with a deleted path
+			gone[dir+string(filepath.Separator)] = true
+			candidates = append(candidates, dir)
+		}
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		if di, dj := depth(root, candidates[i]), depth(root, candidates[j]); di != dj {
+			return di > dj
+		}
+		return candidates[i] < candidates[j]
+	})
+
+	var empty []string
+	for _, dir := range candidates {
+		entries, err := os.ReadDir(dir)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		isEmpty := true
+		for _, entry := range entries {
+			if !gone[filepath.Join(dir, entry.Name())] {
+				isEmpty = false
+				break
+			}
+		}
+		if isEmpty {
+			gone[dir] = true
+			empty = append(empty, dir)
+		}
+	}
+	return empty, nil
+}
+
+// DeleteEmptyDirectory removes a directory if it is empty, refusing anything but a real directory.
+func (l *LocalProvider) DeleteEmptyDirectory(fullPath string) error {
+	info, err := os.Lstat(fullPath)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("refusing to delete %s: not a directory", fullPath)
+	}
+	return os.Remove(fullPath)
+}
+
+// isBelow reports whether the cleaned path is strictly below the cleaned root.
+func isBelow(root, path string) bool {
+	rel, err := filepath.Rel(root, path)
+	return err == nil && rel != "." && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// ListFiles lists the files under the full path with the default options.
 func (l *LocalProvider) ListFiles(fullPath string) ([]*providers.FileInfo, error) {
+	return l.ListFilesWithOptions(fullPath, providers.ListOptions{})
+}
+
+// ListFilesWithOptions traverses the local directory specified by fullPath and returns a list of files, pruning the
+// subdirectories below the maximum depth of the options, if any. In directory mode it lists its subdirectories instead.
+// The local filesystem only exposes the modification time, so every timestamp source falls back to it.
+// Files carry their inode on Unix, so hard links shared between backups are told apart. Files modified within
+// the quiet period, or whose size or modification time changes over the settle interval, are marked in progress.
+func (l *LocalProvider) ListFilesWithOptions(fullPath string, opts providers.ListOptions) ([]*providers.FileInfo, error) {
+	if opts.Directories {
+		return l.listDirectories(fullPath, opts)
+	}
+
+	files, infos, err := walkFiles(fullPath, opts)
+	if err != nil {
+		return nil, err
+	}
+	markInProgress(files, infos, opts)
+	return files, nil
+}
+
+// walkFiles lists the files below the path, along with what the walk found about each of them.
+func walkFiles(fullPath string, opts providers.ListOptions) ([]*providers.FileInfo, []fs.FileInfo, error) {
 	var files []*providers.FileInfo
+	var infos []fs.FileInfo
 
-	err := filepath.Walk(fullPath, func(path string, info os.FileInfo, err error) error {
+	err := filepath.WalkDir(fullPath, func(path string, entry fs.DirEntry, err error) error {
 		if err != nil {
 			return err
 		}
-		if !info.IsDir() {
-			files = append(files, &providers.FileInfo{
-				Path:      path,
-				Size:      info.Size(),
-				Timestamp: carbon.FromStdTime(info.ModTime()),
-			})
+		if entry.IsDir() {
+			if opts.MaxDepth > 0 && path != fullPath && depth(fullPath, path) >= opts.MaxDepth {
+				return filepath.SkipDir
+			}
+			return nil
 		}
+
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		timestamp, source := opts.TimestampSource.Resolve(providers.Timestamps{Modified: info.ModTime()})
+		files = append(files, &providers.FileInfo{
+			Path:            path,
+			Size:            info.Size(),
+			Timestamp:       timestamp,
+			TimestampSource: source,
+			Inodes:          inodeOf(info),
+		})
+		infos = append(infos, info)
 		return nil
 	})
 
+	if err != nil {
+		return nil, nil, err
+	}
+	return files, infos, nil
+}
+
+// markInProgress marks the files still being written, waiting the settle interval once for all of them.
+func markInProgress(files []*providers.FileInfo, infos []fs.FileInfo, opts providers.ListOptions) {
+	if opts.QuietPeriod <= 0 && opts.SettleInterval <= 0 {
+		return
+	}
+
+	now := time.Now()
+	time.Sleep(opts.SettleInterval)
+	for i, file := range files {
+		file.InProgress = inProgress(file.Path, infos[i], now, opts)
+	}
+}
+
+// inProgress reports whether a file is still being written: modified within the quiet period, or changed
+// over the settle interval. A file gone in the meantime is in progress too, most likely renamed once complete.
+func inProgress(path string, listed fs.FileInfo, now time.Time, opts providers.ListOptions) bool {
+	if opts.QuietPeriod > 0 && now.Sub(listed.ModTime()) < opts.QuietPeriod {
+		return true
+	}
+	if opts.SettleInterval <= 0 {
+		return false
+	}
+	info, err := os.Lstat(path)
+	if err != nil {
+		return true
+	}
+	return info.Size() != listed.Size() || !info.ModTime().Equal(listed.ModTime())
+}
+
+// listDirectories lists each subdirectory of the path as one entry, with the size and newest modification
+// time of its tree, or its own modification time when empty. Symbolic links are skipped.
+func (l *LocalProvider) listDirectories(fullPath string, opts providers.ListOptions) ([]*providers.FileInfo, error) {
+	entries, err := os.ReadDir(fullPath)
 	if err != nil {
 		return nil, err
 	}
 
-	return files, nil
+	var paths []string
+	var modTimes []time.Time
+	var trees [][]*providers.FileInfo
+	var all []*providers.FileInfo
+	var allInfos []fs.FileInfo
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+
+		path := filepath.Join(fullPath, entry.Name())
+		info, err := entry.Info()
+		if err != nil {
+			return nil, err
+		}
+		files, infos, err := walkFiles(path, providers.ListOptions{})
+		if err != nil {
+			return nil, err
+		}
+		paths = append(paths, path)
+		modTimes = append(modTimes, info.ModTime())
+		trees = append(trees, files)
+		all = append(all, files...)
+		allInfos = append(allInfos, infos...)
+	}
+	markInProgress(all, allInfos, opts)
+
+	var dirs []*providers.FileInfo
+	for i, path := range paths {
+		dirs = append(dirs, providers.DirectoryInfo(path, trees[i], modTimes[i], opts))
+	}
+	return dirs, nil
+}
+
+// depth returns how many levels below the root the path is, 1 for its direct children.
+func depth(root, path string) int {
+	rel, err := filepath.Rel(root, path)
+	if err != nil {
+		return 0
+	}
+	return strings.Count(rel, string(filepath.Separator)) + 1
 }
@@ -18,9 +18,13 @@ package files_test
 
 import (
 	"os"
+	"path/filepath"
+	"reflect"
 	"testing"
+	"time"
 
 	"github.com/raniellyferreira/rotate-files/pkg/files"
+	"github.com/raniellyferreira/rotate-files/pkg/providers"
 )
 
 func TestLocalProvider_ListFiles(t *testing.T) {
@@ -58,6 +62,161 @@ func TestLocalProvider_ListFiles(t *testing.T) {
 		}
 	})
 
+	t.Run("Teste com profundidade máxima", func(t *testing.T) {
+		dirPath := t.TempDir()
+		filePaths := []string{
+			"file1.txt",
+			"daily/file2.txt",
+			"daily/archive/file3.txt",
+			"daily/archive/old/file4.txt",
+		}
+
+		for _, path := range filePaths {
+			path = filepath.Join(dirPath, path)
+			if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(path, nil, 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		for maxDepth, expectedLen := range map[int]int{0: 4, 1: 1, 2: 2, 3: 3, 4: 4} {
+			backups, err := provider.ListFilesWithOptions(dirPath, providers.ListOptions{MaxDepth: maxDepth})
+			if err != nil {
+				t.Errorf("Erro inesperado: %v", err)
+			}
+
+			if len(backups) != expectedLen {
+				t.Errorf("Resultado incorreto com profundidade %d. Esperado: %d arquivos, Obtido: %d arquivos", maxDepth, expectedLen, len(backups))
+			}
+		}
+	})
+
+	t.Run("Teste com diretórios como backups", func(t *testing.T) {
+		dirPath := t.TempDir()
+		filePaths := map[string]int{
+			"2024-10-01T00:00/base.tar":      100,
+			"2024-10-01T00:00/wal/0001":      10,
+			"2024-10-02T00:00/base.tar":      200,
+			"2024-10-02T00:00/wal/sub/0002":  20,
+			"loose-file-ignored-as-a-backup": 5,
+		}
+
+		for path, size := range filePaths {
+			path = filepath.Join(dirPath, path)
+			if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(path, make([]byte, size), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := os.Symlink(filepath.Join(dirPath, "2024-10-01T00:00"), filepath.Join(dirPath, "latest")); err != nil {
+			t.Fatal(err)
+		}
+
+		backups, err := provider.ListFilesWithOptions(dirPath, providers.ListOptions{Directories: true})
+		if err != nil {
+			t.Errorf("Erro inesperado: %v", err)
+		}
+
+		if len(backups) != 2 {
+			t.Fatalf("Resultado incorreto. Esperado: 2 diretórios, Obtido: %d", len(backups))
+		}
+		if !backups[0].Directory || backups[0].Size != 110 || backups[1].Size != 220 {
+			t.Errorf("Tamanho incorreto dos diretórios: %d, %d", backups[0].Size, backups[1].Size)
+		}
+	})
+
+	t.Run("Teste com arquivos em escrita", func(t *testing.T) {
+		dirPath := t.TempDir()
+		for _, name := range []string{"old/done.sql", "new/dump.sql"} {
+			path := filepath.Join(dirPath, name)
+			if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(path, []byte("data"), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		old := time.Now().Add(-time.Hour)
+		if err := os.Chtimes(filepath.Join(dirPath, "old", "done.sql"), old, old); err != nil {
+			t.Fatal(err)
+		}
+
+		backups, err := provider.ListFilesWithOptions(dirPath, providers.ListOptions{QuietPeriod: 10 * time.Minute})
+		if err != nil {
+			t.Fatalf("Erro inesperado: %v", err)
+		}
+		inProgress := make(map[string]bool)
+		for _, backup := range backups {
+			inProgress[filepath.Base(backup.Path)] = backup.InProgress
+		}
+		if inProgress["done.sql"] || !inProgress["dump.sql"] {
+			t.Errorf("Resultado incorreto. Esperado: dump.sql em escrita, Obtido: %v", inProgress)
+		}
+
+		backups, err = provider.ListFilesWithOptions(dirPath, providers.ListOptions{QuietPeriod: 10 * time.Minute, Directories: true})
+		if err != nil {
+			t.Fatalf("Erro inesperado: %v", err)
+		}
+		if len(backups) != 2 || !backups[0].InProgress || backups[1].InProgress {
+			t.Errorf("Resultado incorreto. Esperado: o diretório new em escrita, Obtido: %v", backups)
+		}
+
+		backups, err = provider.ListFiles(dirPath)
+		if err != nil {
+			t.Fatalf("Erro inesperado: %v", err)
+		}
+		for _, backup := range backups {
+			if backup.InProgress {
+				t.Errorf("Sem período de espera, nenhum arquivo deveria estar em escrita: %s", backup.Path)
+			}
+		}
+	})
+
+	t.Run("Teste com arquivos alterados durante a espera", func(t *testing.T) {
+		dirPath := t.TempDir()
+		old := time.Now().Add(-time.Hour)
+		for _, name := range []string{"done.sql", "growing.sql"} {
+			path := filepath.Join(dirPath, name)
+			if err := os.WriteFile(path, []byte("data"), 0644); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.Chtimes(path, old, old); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		// A writer keeping an old modification time still grows the file while it is listed
+		done := make(chan error)
+		go func() {
+			time.Sleep(50 * time.Millisecond)
+			file, err := os.OpenFile(filepath.Join(dirPath, "growing.sql"), os.O_APPEND|os.O_WRONLY, 0644)
+			if err == nil {
+				_, err = file.Write([]byte("more"))
+				file.Close()
+			}
+			done <- err
+		}()
+
+		backups, err := provider.ListFilesWithOptions(dirPath, providers.ListOptions{QuietPeriod: 10 * time.Minute, SettleInterval: 500 * time.Millisecond})
+		if err != nil {
+			t.Fatalf("Erro inesperado: %v", err)
+		}
+		if err := <-done; err != nil {
+			t.Fatal(err)
+		}
+		inProgress := make(map[string]bool)
+		for _, backup := range backups {
+			inProgress[filepath.Base(backup.Path)] = backup.InProgress
+		}
+		if inProgress["done.sql"] || !inProgress["growing.sql"] {
+			t.Errorf("Resultado incorreto. Esperado: growing.sql em escrita, Obtido: %v", inProgress)
+		}
+	})
+
 	t.Run("Teste com diretório inexistente", func(t *testing.T) {
 		dirPath := "nonexistentdir"
 
@@ -100,3 +259,112 @@ func TestLocalProvider_Delete(t *testing.T) {
 		}
 	})
 }
+
+func TestLocalProvider_DeleteDirectory(t *testing.T) {
+	provider := files.NewLocalProvider()
+
+	t.Run("Teste com diretório existente", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "2024-10-01T00:00")
+		if err := os.MkdirAll(filepath.Join(path, "wal"), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(path, "wal", "0001"), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := provider.DeleteDirectory(path); err != nil {
+			t.Errorf("Erro inesperado: %v", err)
+		}
+
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Errorf("O diretório não foi excluído corretamente")
+		}
+	})
+
+	t.Run("Teste com link simbólico", func(t *testing.T) {
+		dirPath := t.TempDir()
+		target := filepath.Join(dirPath, "target")
+		if err := os.Mkdir(target, 0755); err != nil {
+			t.Fatal(err)
+		}
+		link := filepath.Join(dirPath, "link")
+		if err := os.Symlink(target, link); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := provider.DeleteDirectory(link); err == nil {
+			t.Errorf("Esperava um erro, mas nenhum ocorreu")
+		}
+
+		if _, err := os.Stat(target); err != nil {
+			t.Errorf("O destino do link não deveria ser excluído: %v", err)
+		}
+	})
+}
+
+func TestLocalProvider_EmptyDirectories(t *testing.T) {
+	provider := files.NewLocalProvider()
+	root := t.TempDir()
+
+	var deleted []string
+	for _, path := range []string{"2023/07/a.tar", "2023/08/b.tar", "2023/08/keep.tar", "2024/01/c.tar", "x.tar"} {
+		path = filepath.Join(root, filepath.FromSlash(path))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+		if filepath.Base(path) != "keep.tar" {
+			deleted = append(deleted, path)
+		}
+	}
+	expected := []string{
+		filepath.Join(root, "2023", "07"),
+		filepath.Join(root, "2024", "01"),
+		filepath.Join(root, "2024"),
+	}
+
+	t.Run("Teste antes da exclusão", func(t *testing.T) {
+		dirs, err := provider.EmptyDirectories(root, deleted)
+		if err != nil {
+			t.Fatalf("Erro inesperado: %v", err)
+		}
+		if !reflect.DeepEqual(dirs, expected) {
+			t.Errorf("Resultado incorreto. Esperado: %v, Obtido: %v", expected, dirs)
+		}
+	})
+
+	t.Run("Teste depois da exclusão", func(t *testing.T) {
+		for _, path := range deleted {
+			if err := provider.Delete(path); err != nil {
+				t.Fatal(err)
+			}
+		}
+		dirs, err := provider.EmptyDirectories(root, deleted)
+		if err != nil {
+			t.Fatalf("Erro inesperado: %v", err)
+		}
+		if !reflect.DeepEqual(dirs, expected) {
+			t.Errorf("Resultado incorreto. Esperado: %v, Obtido: %v", expected, dirs)
+		}
+
+		for _, dir := range dirs {
+			if err := provider.DeleteEmptyDirectory(dir); err != nil {
+				t.Errorf("Erro inesperado: %v", err)
+			}
+		}
+		if _, err := os.Stat(filepath.Join(root, "2023", "08", "keep.tar")); err != nil {
+			t.Errorf("O arquivo mantido não deveria ser excluído: %v", err)
+		}
+		if _, err := os.Stat(root); err != nil {
+			t.Errorf("A raiz não deveria ser excluída: %v", err)
+		}
+	})
+
+	t.Run("Teste com diretório não vazio", func(t *testing.T) {
+		if err := provider.DeleteEmptyDirectory(filepath.Join(root, "2023")); err == nil {
+			t.Errorf("Esperava um erro, mas nenhum ocorreu")
+		}
+	})
+}
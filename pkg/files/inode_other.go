@@ -0,0 +1,30 @@
+//go:build !unix
+
+/*
+Copyright The Rotate Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package files
+
+import (
+	"io/fs"
+
+	"github.com/raniellyferreira/rotate-files/pkg/providers"
+)
+
+// inodeOf returns no inode, since hard links can't be told apart on this platform.
+func inodeOf(info fs.FileInfo) []providers.Inode {
+	return nil
+}
@@ -0,0 +1,62 @@
+//go:build unix
+
+/*
+Copyright The Rotate Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package files_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/raniellyferreira/rotate-files/pkg/files"
+	"github.com/raniellyferreira/rotate-files/pkg/providers"
+)
+
+func TestLocalProvider_ListFilesHardLinks(t *testing.T) {
+	provider := files.NewLocalProvider()
+	dirPath := t.TempDir()
+
+	for _, dir := range []string{"daily.0", "daily.1"} {
+		if err := os.Mkdir(filepath.Join(dirPath, dir), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(dirPath, "daily.1", "base.tar"), make([]byte, 10000), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Link(filepath.Join(dirPath, "daily.1", "base.tar"), filepath.Join(dirPath, "daily.0", "base.tar")); err != nil {
+		t.Fatal(err)
+	}
+
+	backups, err := provider.ListFilesWithOptions(dirPath, providers.ListOptions{Directories: true})
+	if err != nil {
+		t.Fatalf("Erro inesperado: %v", err)
+	}
+
+	if len(backups) != 2 || len(backups[0].Inodes) != 1 || len(backups[1].Inodes) != 1 {
+		t.Fatalf("Resultado incorreto. Esperado: 2 diretórios com 1 inode, Obtido: %v", backups)
+	}
+
+	first, second := backups[0].Inodes[0], backups[1].Inodes[0]
+	if first.Number != second.Number || first.Device != second.Device || first.Links != 2 {
+		t.Errorf("Os links deveriam compartilhar o inode: %+v, %+v", first, second)
+	}
+	if first.Allocated <= 0 {
+		t.Errorf("Tamanho alocado incorreto: %d", first.Allocated)
+	}
+}
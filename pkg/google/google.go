@@ -18,9 +18,10 @@ package google
 import (
 	"context"
 	"fmt"
+	"strings"
+	"time"
 
 	"cloud.google.com/go/storage"
-	"github.com/golang-module/carbon"
 	"github.com/raniellyferreira/rotate-files/internal/environment"
 	"github.com/raniellyferreira/rotate-files/internal/utils"
 	"github.com/raniellyferreira/rotate-files/pkg/providers"
@@ -49,10 +50,84 @@ func (g *GoogleProvider) Delete(fullPath string) error {
 	return obj.Delete(context.Background())
 }
 
-// ListFiles retrieves and lists all objects within a Google Cloud Storage bucket with the given full path.
+// defaultTimestampSource is the timestamp source of objects when none is selected: their creation time.
+var defaultTimestampSource = providers.TimestampSource{Kind: providers.SourceCreated}
+
+// ListFiles lists the files under the full path with the default options.
 func (g *GoogleProvider) ListFiles(fullPath string) ([]*providers.FileInfo, error) {
-	bucket, prefix := utils.GetBucketAndKey(fullPath)
+	return g.ListFilesWithOptions(fullPath, providers.ListOptions{})
+}
+
+// ListFilesWithOptions retrieves and lists all objects within a Google Cloud Storage bucket with the given full path,
+// a directory unless the options ask for a raw prefix.
+// Google Cloud Storage has no object tags, so the tag source falls back to the modification time.
+func (g *GoogleProvider) ListFilesWithOptions(fullPath string, opts providers.ListOptions) ([]*providers.FileInfo, error) {
+	bucket, prefix := utils.GetBucketAndPrefix(fullPath, opts.RawPrefix)
+	if opts.Directories {
+		return g.listDirectories(bucket, prefix, opts)
+	}
+	return g.listFiles(bucket, prefix, opts.MaxDepth, opts)
+}
+
+// listDirectories lists each prefix under the prefix as one entry, with the size and newest
+// modification time of its objects.
+func (g *GoogleProvider) listDirectories(bucket, prefix string, opts providers.ListOptions) ([]*providers.FileInfo, error) {
+	it := g.client.Bucket(bucket).Objects(context.Background(), &storage.Query{Prefix: prefix, Delimiter: "/"})
+
+	var dirs []*providers.FileInfo
+	for {
+		objAttrs, err := it.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if objAttrs.Prefix == "" {
+			continue
+		}
+
+		files, err := g.listFiles(bucket, objAttrs.Prefix, 0, providers.ListOptions{})
+		if err != nil {
+			return nil, err
+		}
+		path := fmt.Sprintf("gs://%s/%s", bucket, strings.TrimSuffix(objAttrs.Prefix, "/"))
+		dirs = append(dirs, providers.DirectoryInfo(path, files, time.Time{}, opts))
+	}
+	return dirs, nil
+}
+
+// DeleteDirectory removes every object under a directory listed in directory mode, folder markers included.
+// It refuses the bucket root.
+func (g *GoogleProvider) DeleteDirectory(fullPath string) error {
+	bucket, prefix := utils.GetBucketAndPrefix(fullPath, false)
+	if prefix == "" {
+		return fmt.Errorf("refusing to delete the root of bucket %s", bucket)
+	}
+
 	it := g.client.Bucket(bucket).Objects(context.Background(), &storage.Query{Prefix: prefix})
+	for {
+		objAttrs, err := it.Next()
+		if err == iterator.Done {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := g.client.Bucket(bucket).Object(objAttrs.Name).Delete(context.Background()); err != nil {
+			return err
+		}
+	}
+}
+
+// listFiles lists the objects under the prefix, up to depth levels deep when the depth is positive: listing
+// with a delimiter returns the objects of the level and its subdirectories as prefixes, listed in turn.
+func (g *GoogleProvider) listFiles(bucket, prefix string, depth int, opts providers.ListOptions) ([]*providers.FileInfo, error) {
+	query := &storage.Query{Prefix: prefix}
+	if depth > 0 {
+		query.Delimiter = "/"
+	}
+	it := g.client.Bucket(bucket).Objects(context.Background(), query)
 
 	var files []*providers.FileInfo
 	for {
@@ -63,11 +138,30 @@ func (g *GoogleProvider) ListFiles(fullPath string) ([]*providers.FileInfo, erro
 		if err != nil {
 			return nil, err
 		}
+		if objAttrs.Prefix != "" {
+			if depth > 1 {
+				subFiles, err := g.listFiles(bucket, objAttrs.Prefix, depth-1, opts)
+				if err != nil {
+					return nil, err
+				}
+				files = append(files, subFiles...)
+			}
+			continue
+		}
+		if utils.IsFolderMarker(objAttrs.Name) {
+			continue
+		}
 
+		timestamp, source := opts.TimestampSource.OrDefault(defaultTimestampSource).Resolve(providers.Timestamps{
+			Modified: objAttrs.Updated,
+			Created:  objAttrs.Created,
+			Metadata: objAttrs.Metadata,
+		})
 		files = append(files, &providers.FileInfo{
-			Path:      fmt.Sprintf("gs://%s/%s", bucket, objAttrs.Name),
-			Size:      objAttrs.Size,
-			Timestamp: carbon.CreateFromTimestamp(objAttrs.Created.Unix()),
+			Path:            fmt.Sprintf("gs://%s/%s", bucket, objAttrs.Name),
+			Size:            objAttrs.Size,
+			Timestamp:       timestamp,
+			TimestampSource: source,
 		})
 	}
 
@@ -0,0 +1,146 @@
+/*
+Copyright The Rotate Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package google_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/raniellyferreira/rotate-files/pkg/google"
+	"github.com/raniellyferreira/rotate-files/pkg/providers"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeGCS serves the JSON object listing of a bucket holding the given names, matching them by prefix like
+// Cloud Storage does, and rolling up the names below the delimiter into prefixes.
+func fakeGCS(t *testing.T, bucket string, names []string) {
+	type object struct {
+		Bucket  string `json:"bucket"`
+		Name    string `json:"name"`
+		Size    string `json:"size"`
+		Created string `json:"timeCreated"`
+		Updated string `json:"updated"`
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/storage/v1/b/"+bucket+"/o" {
+			http.NotFound(w, r)
+			return
+		}
+		prefix, delimiter := r.URL.Query().Get("prefix"), r.URL.Query().Get("delimiter")
+		items, prefixes := []object{}, []string{}
+		for _, name := range names {
+			if !strings.HasPrefix(name, prefix) {
+				continue
+			}
+			if i := strings.Index(name[len(prefix):], delimiter); delimiter != "" && i >= 0 {
+				if common := name[:len(prefix)+i+1]; !slices.Contains(prefixes, common) {
+					prefixes = append(prefixes, common)
+				}
+				continue
+			}
+			items = append(items, object{Bucket: bucket, Name: name, Size: "10", Created: "2024-06-14T08:00:00Z", Updated: "2024-06-15T10:00:00Z"})
+		}
+		w.Header().Set("Content-Type", "application/json")
+		assert.NoError(t, json.NewEncoder(w).Encode(map[string]any{"kind": "storage#objects", "items": items, "prefixes": prefixes}))
+	}))
+	t.Cleanup(server.Close)
+
+	t.Setenv("STORAGE_EMULATOR_HOST", server.URL)
+}
+
+func TestListFilesPrefixBoundary(t *testing.T) {
+	fakeGCS(t, "bucket", []string{
+		"backups/",
+		"backups/db-1.sql",
+		"backups/db-2.sql",
+		"backups-old/db-0.sql",
+		"backups2/db-0.sql",
+	})
+
+	provider, err := google.NewGoogleProvider()
+	assert.NoError(t, err)
+
+	for _, path := range []string{"gs://bucket/backups", "gs://bucket/backups/"} {
+		files, err := provider.ListFiles(path)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"gs://bucket/backups/db-1.sql", "gs://bucket/backups/db-2.sql"}, paths(files), path)
+	}
+
+	files, err := provider.ListFilesWithOptions("gs://bucket/backups", providers.ListOptions{RawPrefix: true})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		"gs://bucket/backups/db-1.sql",
+		"gs://bucket/backups/db-2.sql",
+		"gs://bucket/backups-old/db-0.sql",
+		"gs://bucket/backups2/db-0.sql",
+	}, paths(files))
+}
+
+func TestListFilesMaxDepth(t *testing.T) {
+	fakeGCS(t, "bucket", []string{
+		"backups/db-1.sql",
+		"backups/daily/db-2.sql",
+		"backups/daily/archive/db-3.sql",
+		"backups-old/db-0.sql",
+	})
+
+	provider, err := google.NewGoogleProvider()
+	assert.NoError(t, err)
+
+	for maxDepth, expected := range map[int][]string{
+		1: {"gs://bucket/backups/db-1.sql"},
+		2: {"gs://bucket/backups/db-1.sql", "gs://bucket/backups/daily/db-2.sql"},
+		0: {"gs://bucket/backups/daily/archive/db-3.sql", "gs://bucket/backups/daily/db-2.sql", "gs://bucket/backups/db-1.sql"},
+	} {
+		files, err := provider.ListFilesWithOptions("gs://bucket/backups", providers.ListOptions{MaxDepth: maxDepth})
+		assert.NoError(t, err)
+		assert.ElementsMatch(t, expected, paths(files), maxDepth)
+	}
+}
+
+func TestListFilesDefaultTimestampSource(t *testing.T) {
+	fakeGCS(t, "bucket", []string{"backups/db-1.sql"})
+
+	provider, err := google.NewGoogleProvider()
+	assert.NoError(t, err)
+
+	// Objects were rotated by their creation time before the source was selectable, and still are by default
+	files, err := provider.ListFiles("gs://bucket/backups")
+	assert.NoError(t, err)
+	assert.Equal(t, "2024-06-14 08:00:00", files[0].Timestamp.SetTimezone("UTC").ToDateTimeString())
+	assert.Equal(t, providers.SourceCreated, files[0].TimestampSource)
+
+	files, err = provider.ListFilesWithOptions("gs://bucket/backups", providers.ListOptions{
+		TimestampSource: providers.TimestampSource{Kind: providers.SourceModified},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "2024-06-15 10:00:00", files[0].Timestamp.SetTimezone("UTC").ToDateTimeString())
+}
+
+// paths returns the paths of the listed files.
+func paths(files []*providers.FileInfo) []string {
+	var result []string
+	for _, file := range files {
+		result = append(result, file.Path)
+	}
+	return result
+}
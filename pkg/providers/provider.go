@@ -17,13 +17,56 @@ limitations under the License.
 package providers
 
 import (
+	"errors"
+	"time"
+
 	"github.com/golang-module/carbon"
 )
 
+// ErrUploadsNotListed is returned, wrapped, along with the listed files when the uploads in progress can't
+// be listed, such as S3 multipart uploads without the s3:ListBucketMultipartUploads permission. The files
+// are listed as usual, but the uploads are not reported.
+var ErrUploadsNotListed = errors.New("uploads in progress can't be listed, they are not reported")
+
+// FileInfo describes a listed file. A Directory entry stands for a whole directory tree, listed in
+// directory mode, with the total size of its files and the timestamp of the newest one.
+// Inodes holds the inode of the file, or those of the files of a directory, when the provider knows them.
+// InProgress marks a file still being written or uploaded, or a directory holding one, which is never rotated.
 type FileInfo struct {
-	Path      string
-	Size      int64
-	Timestamp carbon.Carbon
+	Path            string
+	Size            int64
+	Timestamp       carbon.Carbon
+	TimestampSource string
+	Directory       bool
+	Inodes          []Inode
+	InProgress      bool
+}
+
+// Inode identifies the data of a file on its device, shared by all its hard links, with its
+// number of links and the disk space allocated to it.
+type Inode struct {
+	Device    uint64
+	Number    uint64
+	Links     uint64
+	Allocated int64
+}
+
+// ListOptions configures how a provider lists files.
+// Cloud paths are directories, so "s3://bucket/backups" lists "backups/" and not "backups-old/";
+// RawPrefix lists every key starting with the path as given instead, such as "s3://bucket/backups/db-".
+// MaxDepth limits how deep files are listed below the path: 1 lists only its direct children,
+// 2 those of its subdirectories too, and 0 lists the whole tree.
+// Directories lists each direct subdirectory of the path as a single entry instead of files, for
+// tools writing one directory per backup; the maximum depth doesn't apply to their trees.
+// QuietPeriod marks the local files modified more recently than it as in progress, and SettleInterval
+// those whose size or modification time changes over it, checked once for the whole listing; 0 disables a check.
+type ListOptions struct {
+	TimestampSource TimestampSource
+	RawPrefix       bool
+	MaxDepth        int
+	Directories     bool
+	QuietPeriod     time.Duration
+	SettleInterval  time.Duration
 }
 
 // Provider defines the interface for cloud storage operations such as delete and list files.
@@ -31,3 +74,40 @@ type Provider interface {
 	Delete(fullPath string) error
 	ListFiles(fullPath string) ([]*FileInfo, error)
 }
+
+// OptionsLister is implemented by providers that can list files with options. Providers implementing
+// only Provider list every file with the default options.
+type OptionsLister interface {
+	ListFilesWithOptions(fullPath string, opts ListOptions) ([]*FileInfo, error)
+}
+
+// DirectoryDeleter is implemented by providers that can delete the directory entries they list
+// in directory mode, removing the whole tree.
+type DirectoryDeleter interface {
+	DeleteDirectory(fullPath string) error
+}
+
+// EmptyDirectoryRemover is implemented by providers whose directories outlive the files deleted from them,
+// such as the local filesystem, leaving empty date-based folders behind.
+type EmptyDirectoryRemover interface {
+	// EmptyDirectories returns the directories strictly below the root left empty once the deleted paths are gone, deepest first.
+	EmptyDirectories(root string, deleted []string) ([]string, error)
+	// DeleteEmptyDirectory removes a directory only when it is empty.
+	DeleteEmptyDirectory(fullPath string) error
+}
+
+// DirectoryInfo returns the entry of a directory holding the files: their total size, and the modification
+// time of the newest one, or the given time when there is none. It is in progress when one of the files is.
+func DirectoryInfo(path string, files []*FileInfo, modified time.Time, opts ListOptions) *FileInfo {
+	info := &FileInfo{Path: path, Directory: true}
+	for i, file := range files {
+		info.Size += file.Size
+		info.Inodes = append(info.Inodes, file.Inodes...)
+		info.InProgress = info.InProgress || file.InProgress
+		if t := file.Timestamp.ToStdTime(); i == 0 || t.After(modified) {
+			modified = t
+		}
+	}
+	info.Timestamp, info.TimestampSource = opts.TimestampSource.Resolve(Timestamps{Modified: modified})
+	return info
+}
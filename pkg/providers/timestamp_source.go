@@ -0,0 +1,197 @@
+/*
+Copyright The Rotate Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package providers
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang-module/carbon"
+)
+
+// Timestamp source kinds.
+const (
+	SourceDefault  = "default"
+	SourceModified = "modified"
+	SourceCreated  = "created"
+	SourceMetadata = "metadata"
+	SourceTag      = "tag"
+)
+
+// metadataPrefixes are the header prefixes providers may leave on user metadata keys.
+var metadataPrefixes = []string{"x-amz-meta-", "x-goog-meta-", "x-ms-meta-"}
+
+// TimestampSource selects which object attribute provides the backup timestamp.
+// The zero value, parsed from "default", selects the provider's own default: the creation time on
+// Cloud Storage and Azure, the modification time elsewhere.
+type TimestampSource struct {
+	Kind string
+	Key  string
+}
+
+// ParseTimestampSource parses "default", "modified", "created", "metadata:<key>" or "tag:<key>".
+func ParseTimestampSource(value string) (TimestampSource, error) {
+	kind, key, _ := strings.Cut(strings.TrimSpace(value), ":")
+	kind = strings.ToLower(kind)
+
+	switch kind {
+	case SourceDefault:
+		if key != "" {
+			return TimestampSource{}, fmt.Errorf("invalid timestamp source %q: %s takes no key", value, kind)
+		}
+		return TimestampSource{}, nil
+	case SourceModified, SourceCreated:
+		if key != "" {
+			return TimestampSource{}, fmt.Errorf("invalid timestamp source %q: %s takes no key", value, kind)
+		}
+		return TimestampSource{Kind: kind}, nil
+	case SourceMetadata, SourceTag:
+		if key == "" {
+			return TimestampSource{}, fmt.Errorf("invalid timestamp source %q: missing %s key", value, kind)
+		}
+		return TimestampSource{Kind: kind, Key: key}, nil
+	default:
+		return TimestampSource{}, fmt.Errorf("invalid timestamp source %q", value)
+	}
+}
+
+// String returns the source in the same form accepted by ParseTimestampSource.
+func (s TimestampSource) String() string {
+	if s.Kind == "" {
+		return SourceDefault
+	}
+	if s.Key != "" {
+		return s.Kind + ":" + s.Key
+	}
+	return s.Kind
+}
+
+// Fallbacks returns the sources tried in order: the selected source, then modified, then created.
+func (s TimestampSource) Fallbacks() []TimestampSource {
+	order := []TimestampSource{s}
+	if s.Kind == "" {
+		order = nil
+	}
+	for _, kind := range []string{SourceModified, SourceCreated} {
+		if s.Kind != kind {
+			order = append(order, TimestampSource{Kind: kind})
+		}
+	}
+	return order
+}
+
+// OrDefault returns the source, or the provider default when the source is the zero value.
+func (s TimestampSource) OrDefault(providerDefault TimestampSource) TimestampSource {
+	if s.Kind == "" {
+		return providerDefault
+	}
+	return s
+}
+
+// NeedsMetadata reports whether the source reads user metadata, which some providers fetch per object.
+func (s TimestampSource) NeedsMetadata() bool {
+	return s.Kind == SourceMetadata
+}
+
+// NeedsTags reports whether the source reads object tags, which some providers fetch per object.
+func (s TimestampSource) NeedsTags() bool {
+	return s.Kind == SourceTag
+}
+
+// Timestamps holds every timestamp candidate a provider knows about an object.
+// Zero times and nil maps mean the provider doesn't expose that attribute.
+type Timestamps struct {
+	Modified time.Time
+	Created  time.Time
+	Metadata map[string]string
+	Tags     map[string]string
+}
+
+// Resolve picks the timestamp following the fallback order, returning it together with the source it came from.
+func (s TimestampSource) Resolve(ts Timestamps) (carbon.Carbon, string) {
+	for _, source := range s.Fallbacks() {
+		if t, ok := source.lookup(ts); ok {
+			return carbon.FromStdTime(t), source.String()
+		}
+	}
+	return carbon.FromStdTime(ts.Modified), SourceModified
+}
+
+// lookup returns the timestamp of a single source.
+func (s TimestampSource) lookup(ts Timestamps) (time.Time, bool) {
+	switch s.Kind {
+	case SourceModified:
+		return ts.Modified, !ts.Modified.IsZero()
+	case SourceCreated:
+		return ts.Created, !ts.Created.IsZero()
+	case SourceMetadata:
+		if value, ok := lookupKey(ts.Metadata, s.Key); ok {
+			return ParseTimestampValue(value)
+		}
+	case SourceTag:
+		if value, ok := lookupKey(ts.Tags, s.Key); ok {
+			return ParseTimestampValue(value)
+		}
+	}
+	return time.Time{}, false
+}
+
+// lookupKey finds a metadata or tag value ignoring case and provider header prefixes.
+func lookupKey(values map[string]string, key string) (string, bool) {
+	key = trimMetadataPrefix(key)
+	for k, v := range values {
+		if trimMetadataPrefix(k) == key {
+			return v, true
+		}
+	}
+	return "", false
+}
+
+// trimMetadataPrefix lowercases a metadata key and removes its provider header prefix.
+func trimMetadataPrefix(key string) string {
+	key = strings.ToLower(key)
+	for _, prefix := range metadataPrefixes {
+		key = strings.TrimPrefix(key, prefix)
+	}
+	return key
+}
+
+// ParseTimestampValue parses a timestamp stored as text: RFC 3339, Unix seconds (optionally
+// fractional, as written by rclone) or an s3cmd attribute string containing "mtime:<seconds>".
+func ParseTimestampValue(value string) (time.Time, bool) {
+	value = strings.TrimSpace(value)
+
+	if t, err := time.Parse(time.RFC3339Nano, value); err == nil {
+		return t, true
+	}
+
+	for _, attr := range strings.Split(value, "/") {
+		if mtime, ok := strings.CutPrefix(attr, "mtime:"); ok {
+			value = mtime
+			break
+		}
+	}
+
+	seconds, err := strconv.ParseFloat(value, 64)
+	if err != nil || seconds <= 0 {
+		return time.Time{}, false
+	}
+	sec := int64(seconds)
+	return time.Unix(sec, int64((seconds-float64(sec))*1e9)), true
+}
@@ -0,0 +1,174 @@
+/*
+Copyright The Rotate Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package providers_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/raniellyferreira/rotate-files/pkg/providers"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseTimestampSource(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected providers.TimestampSource
+		valid    bool
+	}{
+		{"default", providers.TimestampSource{}, true},
+		{"modified", providers.TimestampSource{Kind: providers.SourceModified}, true},
+		{"Created", providers.TimestampSource{Kind: providers.SourceCreated}, true},
+		{"metadata:x-amz-meta-mtime", providers.TimestampSource{Kind: providers.SourceMetadata, Key: "x-amz-meta-mtime"}, true},
+		{"tag:backup-time", providers.TimestampSource{Kind: providers.SourceTag, Key: "backup-time"}, true},
+		{"metadata", providers.TimestampSource{}, false},
+		{"modified:key", providers.TimestampSource{}, false},
+		{"default:key", providers.TimestampSource{}, false},
+		{"accessed", providers.TimestampSource{}, false},
+	}
+
+	for _, test := range tests {
+		source, err := providers.ParseTimestampSource(test.input)
+		if test.valid {
+			assert.NoError(t, err, test.input)
+			assert.Equal(t, test.expected, source, test.input)
+		} else {
+			assert.Error(t, err, test.input)
+		}
+	}
+}
+
+func TestTimestampSource_Resolve(t *testing.T) {
+	modified := time.Date(2024, 5, 10, 12, 0, 0, 0, time.UTC)
+	created := time.Date(2024, 5, 1, 8, 0, 0, 0, time.UTC)
+	mtime := time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	tests := []struct {
+		name           string
+		source         string
+		timestamps     providers.Timestamps
+		expected       time.Time
+		expectedSource string
+	}{
+		{
+			name:           "Modified",
+			source:         "modified",
+			timestamps:     providers.Timestamps{Modified: modified, Created: created},
+			expected:       modified,
+			expectedSource: "modified",
+		},
+		{
+			name:           "Created",
+			source:         "created",
+			timestamps:     providers.Timestamps{Modified: modified, Created: created},
+			expected:       created,
+			expectedSource: "created",
+		},
+		{
+			name:           "Created unavailable falls back to modified",
+			source:         "created",
+			timestamps:     providers.Timestamps{Modified: modified},
+			expected:       modified,
+			expectedSource: "modified",
+		},
+		{
+			name:   "Metadata with provider prefix and RFC 3339 value",
+			source: "metadata:x-amz-meta-mtime",
+			timestamps: providers.Timestamps{
+				Modified: modified,
+				Metadata: map[string]string{"Mtime": mtime.Format(time.RFC3339Nano)},
+			},
+			expected:       mtime,
+			expectedSource: "metadata:x-amz-meta-mtime",
+		},
+		{
+			name:   "Metadata with fractional Unix seconds",
+			source: "metadata:mtime",
+			timestamps: providers.Timestamps{
+				Modified: modified,
+				Metadata: map[string]string{"mtime": "1672628645.000000000"},
+			},
+			expected:       mtime,
+			expectedSource: "metadata:mtime",
+		},
+		{
+			name:   "s3cmd attributes",
+			source: "metadata:s3cmd-attrs",
+			timestamps: providers.Timestamps{
+				Modified: modified,
+				Metadata: map[string]string{"s3cmd-attrs": "atime:1700000000/gid:0/mtime:1672628645/uid:0"},
+			},
+			expected:       mtime,
+			expectedSource: "metadata:s3cmd-attrs",
+		},
+		{
+			name:   "Invalid metadata value falls back to modified",
+			source: "metadata:mtime",
+			timestamps: providers.Timestamps{
+				Modified: modified,
+				Metadata: map[string]string{"mtime": "yesterday"},
+			},
+			expected:       modified,
+			expectedSource: "modified",
+		},
+		{
+			name:   "Tag",
+			source: "tag:backup-time",
+			timestamps: providers.Timestamps{
+				Modified: modified,
+				Tags:     map[string]string{"backup-time": "2023-01-02T03:04:05Z"},
+			},
+			expected:       mtime,
+			expectedSource: "tag:backup-time",
+		},
+		{
+			name:           "Missing tag falls back to created when modified is unknown",
+			source:         "tag:backup-time",
+			timestamps:     providers.Timestamps{Created: created},
+			expected:       created,
+			expectedSource: "created",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			source, err := providers.ParseTimestampSource(test.source)
+			assert.NoError(t, err)
+
+			timestamp, used := source.Resolve(test.timestamps)
+			assert.True(t, test.expected.Equal(timestamp.ToStdTime()), "got %s", timestamp)
+			assert.Equal(t, test.expectedSource, used)
+		})
+	}
+}
+
+func TestTimestampSource_ZeroValue(t *testing.T) {
+	modified := time.Date(2024, 5, 10, 12, 0, 0, 0, time.UTC)
+
+	timestamp, used := providers.TimestampSource{}.Resolve(providers.Timestamps{Modified: modified})
+	assert.True(t, modified.Equal(timestamp.ToStdTime()))
+	assert.Equal(t, "modified", used)
+}
+
+func TestTimestampSource_OrDefault(t *testing.T) {
+	created := providers.TimestampSource{Kind: providers.SourceCreated}
+	modified := providers.TimestampSource{Kind: providers.SourceModified}
+
+	assert.Equal(t, created, providers.TimestampSource{}.OrDefault(created))
+	assert.Equal(t, modified, modified.OrDefault(created))
+	assert.Equal(t, providers.SourceDefault, providers.TimestampSource{}.String())
+}
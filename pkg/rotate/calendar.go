@@ -0,0 +1,69 @@
+/*
+Copyright The Rotate Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rotate
+
+import (
+	"fmt"
+
+	"github.com/golang-module/carbon"
+)
+
+// Bucket keys of the calendar tiers, evaluated in the timestamp's time zone and week start.
+var (
+	hourBucket  = func(c carbon.Carbon) string { return c.ToStdTime().Format("2006-01-02T15") }
+	dayBucket   = func(c carbon.Carbon) string { return c.ToStdTime().Format("2006-01-02") }
+	weekBucket  = func(c carbon.Carbon) string { return c.StartOfWeek().ToStdTime().Format("2006-01-02") }
+	monthBucket = func(c carbon.Carbon) string { return c.ToStdTime().Format("2006-01") }
+)
+
+// minuteBucket returns the bucket key of intervals of the given minutes, counted from midnight.
+func minuteBucket(interval int) func(carbon.Carbon) string {
+	interval = max(interval, 1)
+	return func(c carbon.Carbon) string {
+		t := c.ToStdTime()
+		return fmt.Sprintf("%s/%d", t.Format("2006-01-02"), (t.Hour()*60+t.Minute())/interval)
+	}
+}
+
+// quarterBucket returns the bucket key of quarters anchored to the fiscal year start month.
+func quarterBucket(fiscalYearStart int) func(carbon.Carbon) string {
+	return func(c carbon.Carbon) string {
+		year, quarter := fiscalQuarter(c, fiscalYearStart)
+		return fmt.Sprintf("%d-Q%d", year, quarter)
+	}
+}
+
+// yearBucket returns the bucket key of years starting at the fiscal year start month.
+func yearBucket(fiscalYearStart int) func(carbon.Carbon) string {
+	return func(c carbon.Carbon) string {
+		year, _ := fiscalQuarter(c, fiscalYearStart)
+		return fmt.Sprint(year)
+	}
+}
+
+// fiscalQuarter returns the fiscal year, named after the calendar year it starts in, and the
+// quarter (1 to 4) of the timestamp. A start month outside 1 to 12 means January.
+func fiscalQuarter(c carbon.Carbon, fiscalYearStart int) (int, int) {
+	if fiscalYearStart < 1 || fiscalYearStart > 12 {
+		fiscalYearStart = 1
+	}
+	year, month := c.Year(), c.Month()
+	if month < fiscalYearStart {
+		year--
+	}
+	return year, (month-fiscalYearStart+12)%12/3 + 1
+}
@@ -0,0 +1,237 @@
+/*
+Copyright The Rotate Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rotate_test
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"github.com/golang-module/carbon"
+	"github.com/raniellyferreira/rotate-files/pkg/rotate"
+	"github.com/stretchr/testify/assert"
+)
+
+var calendarScheme = rotate.RotationScheme{
+	Hourly:       6,
+	Daily:        7,
+	Weekly:       4,
+	Monthly:      6,
+	Yearly:       2,
+	Timezone:     "UTC",
+	WeekStartsAt: carbon.Monday,
+	Mode:         rotate.ModeCalendar,
+}
+
+// newestSelection selects the newest backup of every period, instead of the oldest calendar mode selects by default.
+var newestSelection = rotate.TierSelection{
+	Minutely: rotate.SelectNewest, Hourly: rotate.SelectNewest, Daily: rotate.SelectNewest, Weekly: rotate.SelectNewest,
+	Monthly: rotate.SelectNewest, Quarterly: rotate.SelectNewest, Yearly: rotate.SelectNewest,
+}
+
+func TestRotateFilesOfCalendar(t *testing.T) {
+	today := carbon.CreateFromDateTime(2024, 6, 15, 10, 0, 0, "UTC")
+
+	backups := []*rotate.File{
+		{Path: "/b", Timestamp: today.SubDays(1)},
+		{Path: "/a", Timestamp: today.SubDays(1)},
+		{Path: "/yesterday_early", Timestamp: today.SubDays(1).SubHours(8)},
+		{Path: "/two_days", Timestamp: today.SubDays(2)},
+		{Path: "/last_month", Timestamp: carbon.CreateFromDateTime(2024, 5, 2, 3, 0, 0, "UTC")},
+		{Path: "/last_year", Timestamp: carbon.CreateFromDateTime(2023, 3, 2, 3, 0, 0, "UTC")},
+	}
+
+	scheme := rotate.RotationScheme{Daily: 7, Monthly: 12, Yearly: -1, Timezone: "UTC", Mode: rotate.ModeCalendar, Selection: newestSelection}
+	summary := rotate.RotateFilesOf(backups, &scheme, today)
+
+	// Equal timestamps are ordered by path, so "/a" represents the day.
+	assert.Equal(t, []string{"/a", "/two_days", "/last_month", "/last_year"}, paths(summary.Daily))
+	assert.Equal(t, []string{"/a", "/last_month", "/last_year"}, paths(summary.Monthly))
+	assert.Equal(t, []string{"/a", "/last_year"}, paths(summary.Yearly))
+	assert.Equal(t, []string{"/b", "/yesterday_early"}, paths(summary.ForDelete))
+
+	// By default each period keeps its first backup, which later backups of the period don't replace
+	scheme.Selection = rotate.TierSelection{}
+	summary = rotate.RotateFilesOf(backups, &scheme, today)
+	assert.Equal(t, []string{"/yesterday_early", "/two_days", "/last_month", "/last_year"}, paths(summary.Daily))
+	assert.Equal(t, []string{"/two_days", "/last_month", "/last_year"}, paths(summary.Monthly))
+	assert.Equal(t, []string{"/last_month", "/last_year"}, paths(summary.Yearly))
+	assert.Equal(t, []string{"/a", "/b"}, paths(summary.ForDelete))
+}
+
+func TestRotateFilesOfCalendarKeepsOldBuckets(t *testing.T) {
+	// Rolling windows would drop these monthly backups as they are years old;
+	// calendar buckets keep them until newer months fill the tier.
+	today := carbon.CreateFromDateTime(2024, 6, 15, 10, 0, 0, "UTC")
+
+	backups := []*rotate.File{
+		{Path: "/2020-03", Timestamp: carbon.CreateFromDateTime(2020, 3, 1, 0, 0, 0, "UTC")},
+		{Path: "/2020-02", Timestamp: carbon.CreateFromDateTime(2020, 2, 1, 0, 0, 0, "UTC")},
+		{Path: "/2020-01", Timestamp: carbon.CreateFromDateTime(2020, 1, 1, 0, 0, 0, "UTC")},
+	}
+
+	scheme := rotate.RotationScheme{Monthly: 2, Timezone: "UTC", Mode: rotate.ModeCalendar}
+	summary := rotate.RotateFilesOf(backups, &scheme, today)
+
+	assert.Equal(t, []string{"/2020-03", "/2020-02"}, paths(summary.Monthly))
+	assert.Equal(t, []string{"/2020-01"}, paths(summary.ForDelete))
+}
+
+// TestRotateFilesOfCalendarNoChurn simulates months of runs every six hours with irregular backups and
+// checks that no run deletes a file an earlier run kept for a tier that still has room.
+func TestRotateFilesOfCalendarNoChurn(t *testing.T) {
+	rng := rand.New(rand.NewSource(42))
+	start := carbon.CreateFromDateTime(2022, 11, 1, 0, 0, 0, "UTC")
+
+	tiers := []struct {
+		name  string
+		limit int
+		kept  func(*rotate.Summary) []*rotate.File
+		same  func(a, b carbon.Carbon) bool
+	}{
+		{"hourly", calendarScheme.Hourly, func(s *rotate.Summary) []*rotate.File { return s.Hourly }, carbon.Carbon.IsSameHour},
+		{"daily", calendarScheme.Daily, func(s *rotate.Summary) []*rotate.File { return s.Daily }, carbon.Carbon.IsSameDay},
+		{"weekly", calendarScheme.Weekly, func(s *rotate.Summary) []*rotate.File { return s.Weekly }, sameWeek},
+		{"monthly", calendarScheme.Monthly, func(s *rotate.Summary) []*rotate.File { return s.Monthly }, carbon.Carbon.IsSameMonth},
+		{"yearly", calendarScheme.Yearly, func(s *rotate.Summary) []*rotate.File { return s.Yearly }, carbon.Carbon.IsSameYear},
+	}
+
+	var files, pending []*rotate.File
+	var previous *rotate.Summary
+
+	for day := 0; day < 500; day++ {
+		date := start.AddDays(day)
+
+		// Between zero and three backups a day, at random hours.
+		for i := rng.Intn(4); i > 0; i-- {
+			timestamp := date.AddHours(rng.Intn(24)).AddMinutes(rng.Intn(60))
+			pending = append(pending, &rotate.File{Path: fmt.Sprintf("/backup_%s", timestamp.ToDateTimeString()), Timestamp: timestamp})
+		}
+
+		for hour := 5; hour < 24; hour += 6 {
+			run := date.AddHours(hour).AddMinutes(59)
+
+			// Only the backups taken before the run exist yet.
+			var later []*rotate.File
+			for _, file := range pending {
+				if file.Timestamp.Lte(run) {
+					files = append(files, file)
+				} else {
+					later = append(later, file)
+				}
+			}
+			pending = later
+
+			summary := rotate.RotateFilesOf(append([]*rotate.File(nil), files...), &calendarScheme, run)
+
+			deleted := make(map[*rotate.File]bool)
+			for _, file := range summary.ForDelete {
+				deleted[file] = true
+			}
+
+			for _, tier := range tiers {
+				kept := tier.kept(summary)
+				assert.LessOrEqual(t, len(kept), tier.limit, tier.name)
+
+				keptNow := make(map[*rotate.File]bool)
+				for _, file := range kept {
+					keptNow[file] = true
+					assert.False(t, deleted[file], "%s: %s kept and deleted", tier.name, file.Path)
+				}
+
+				// No holes: every remaining file newer than the oldest kept bucket has its bucket represented.
+				if len(kept) > 0 {
+					oldest := kept[len(kept)-1]
+					for _, file := range files {
+						if file.Timestamp.Lt(oldest.Timestamp) || deleted[file] {
+							continue
+						}
+						represented := false
+						for _, k := range kept {
+							if tier.same(file.Timestamp, k.Timestamp) {
+								represented = true
+								break
+							}
+						}
+						assert.True(t, represented, "%s: hole at %s on %s", tier.name, file.Path, run)
+					}
+				}
+
+				// No churn: files dropped from the tier, open buckets included, were pushed out by
+				// a full tier of newer buckets.
+				if previous == nil {
+					continue
+				}
+				for _, file := range tier.kept(previous) {
+					if keptNow[file] {
+						continue
+					}
+					assert.Equal(t, tier.limit, len(kept), "%s: %s dropped with room left on %s", tier.name, file.Path, run)
+					for _, k := range kept {
+						assert.True(t, k.Timestamp.Gt(file.Timestamp), "%s: %s dropped for an older bucket on %s", tier.name, file.Path, run)
+					}
+				}
+			}
+
+			// Apply the deletion before the next run.
+			var remaining []*rotate.File
+			for _, file := range files {
+				if !deleted[file] {
+					remaining = append(remaining, file)
+				}
+			}
+			files = remaining
+			previous = summary
+		}
+	}
+
+	assert.Equal(t, 2, len(previous.Yearly))
+	assert.Equal(t, calendarScheme.Monthly, len(previous.Monthly))
+}
+
+func sameWeek(a, b carbon.Carbon) bool {
+	a, b = a.SetWeekStartsAt(calendarScheme.WeekStartsAt), b.SetWeekStartsAt(calendarScheme.WeekStartsAt)
+	return a.StartOfWeek().IsSameDay(b.StartOfWeek())
+}
+
+func paths(files []*rotate.File) []string {
+	result := make([]string, len(files))
+	for i, file := range files {
+		result[i] = file.Path
+	}
+	return result
+}
+
+func TestRotateFilesOfCalendarFiscalYear(t *testing.T) {
+	today := carbon.CreateFromDateTime(2024, 6, 15, 10, 0, 0, "UTC")
+
+	backups := []*rotate.File{
+		{Path: "/2024-04-01", Timestamp: carbon.CreateFromDateTime(2024, 4, 1, 0, 0, 0, "UTC")},
+		{Path: "/2024-03-31", Timestamp: carbon.CreateFromDateTime(2024, 3, 31, 0, 0, 0, "UTC")},
+		{Path: "/2024-01-15", Timestamp: carbon.CreateFromDateTime(2024, 1, 15, 0, 0, 0, "UTC")},
+		{Path: "/2023-04-01", Timestamp: carbon.CreateFromDateTime(2023, 4, 1, 0, 0, 0, "UTC")},
+		{Path: "/2023-03-31", Timestamp: carbon.CreateFromDateTime(2023, 3, 31, 0, 0, 0, "UTC")},
+	}
+
+	// Fiscal years starting in April: quarters are Apr-Jun, Jul-Sep, Oct-Dec and Jan-Mar
+	scheme := rotate.RotationScheme{Quarterly: -1, Yearly: -1, FiscalYearStart: 4, Timezone: "UTC", Mode: rotate.ModeCalendar, Selection: newestSelection}
+	summary := rotate.RotateFilesOf(backups, &scheme, today)
+
+	assert.Equal(t, []string{"/2024-04-01", "/2024-03-31", "/2023-04-01", "/2023-03-31"}, paths(summary.Quarterly))
+	assert.Equal(t, []string{"/2024-04-01", "/2024-03-31", "/2023-03-31"}, paths(summary.Yearly))
+	assert.Equal(t, []string{"/2024-01-15"}, paths(summary.ForDelete))
+}
@@ -0,0 +1,207 @@
+/*
+Copyright The Rotate Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rotate
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/golang-module/carbon"
+)
+
+// TierChain is the name of the tier keeping the backups other kept backups depend on.
+const TierChain = "chain"
+
+// ChainRule tells which backups are incremental and which backup each incremental depends on.
+// Incremental reports whether a file is an incremental backup, the others being full backups.
+// Parent returns a reference to the parent of an incremental: the parent is the newest older
+// backup whose base name contains it, leaving out the reference an incremental makes to its own parent. Without Parent, an incremental depends on the backup before it.
+type ChainRule struct {
+	Incremental func(*File) bool
+	Parent      func(*File) string
+}
+
+// Chain is a full backup followed by the incrementals depending on it, oldest first.
+// A broken chain starts with an incremental whose parent is missing, so it can't be restored.
+type Chain struct {
+	Files  []*File
+	Broken bool
+}
+
+// ParseChainRule returns the chain rule of backups whose name matches the incremental regexp, such as
+// `-incr-`. The parent regexp is optional: when not empty, its first capture group is the parent reference.
+// Like timestamp patterns, the regexps are matched against the base name, or against the path when they contain "/".
+func ParseChainRule(incremental, parent string) (ChainRule, error) {
+	incrementalRe, err := regexp.Compile(incremental)
+	if err != nil {
+		return ChainRule{}, fmt.Errorf("invalid incremental regexp %q: %w", incremental, err)
+	}
+
+	rule := ChainRule{Incremental: func(file *File) bool {
+		return incrementalRe.MatchString(regexpSubject(incrementalRe, file.Path))
+	}}
+	if parent != "" {
+		parentRe, err := regexp.Compile(parent)
+		if err != nil {
+			return ChainRule{}, fmt.Errorf("invalid parent regexp %q: %w", parent, err)
+		}
+		rule.Parent = GroupByRegexp(parentRe)
+	}
+	return rule, nil
+}
+
+// regexpSubject returns what a regexp is matched against: the path when the regexp contains "/", the base name otherwise.
+func regexpSubject(re *regexp.Regexp, path string) string {
+	if strings.Contains(re.String(), "/") {
+		return path
+	}
+	return baseName(path)
+}
+
+// parents returns the parent of each incremental, missing for those whose parent isn't among the files.
+func (c ChainRule) parents(files []*File) map[*File]*File {
+	sorted := sortedFiles(files)
+	parents := make(map[*File]*File)
+	for i, file := range sorted {
+		if !c.Incremental(file) {
+			continue
+		}
+		older := sorted[i+1:]
+		if c.Parent == nil {
+			if len(older) > 0 {
+				parents[file] = older[0]
+			}
+			continue
+		}
+		if ref := c.Parent(file); ref != "" {
+			for _, candidate := range older {
+				if strings.Contains(c.ownName(candidate), ref) {
+					parents[file] = candidate
+					break
+				}
+			}
+		}
+	}
+	return parents
+}
+
+// ownName returns the base name of a backup without the reference to its own parent, so an older incremental
+// naming the same parent, such as "db-20240611-incr-from-20240610", isn't taken for the backup it references.
+func (c ChainRule) ownName(file *File) string {
+	name := baseName(file.Path)
+	if !c.Incremental(file) {
+		return name
+	}
+	if ref := c.Parent(file); ref != "" {
+		if i := strings.LastIndex(name, ref); i >= 0 {
+			name = name[:i] + name[i+len(ref):]
+		}
+	}
+	return name
+}
+
+// chains returns the chains of the files, oldest first: each full backup or orphaned incremental
+// followed by the incrementals depending on it, directly or not.
+func (c ChainRule) chains(files []*File, parents map[*File]*File) []*Chain {
+	sorted := sortedFiles(files)
+	children := make(map[*File][]*File)
+	for i := len(sorted) - 1; i >= 0; i-- {
+		if parent, ok := parents[sorted[i]]; ok {
+			children[parent] = append(children[parent], sorted[i])
+		}
+	}
+
+	var chains []*Chain
+	for i := len(sorted) - 1; i >= 0; i-- {
+		root := sorted[i]
+		if _, ok := parents[root]; ok {
+			continue
+		}
+		chain := &Chain{Broken: c.Incremental(root)}
+		queue := []*File{root}
+		for len(queue) > 0 {
+			file := queue[0]
+			queue = queue[1:]
+			chain.Files = append(chain.Files, file)
+			queue = append(queue, children[file]...)
+		}
+		// Single full backups aren't worth listing as chains
+		if len(chain.Files) > 1 || chain.Broken {
+			chains = append(chains, chain)
+		}
+	}
+	return chains
+}
+
+// chainPolicy keeps the backups the kept incrementals depend on.
+type chainPolicy struct {
+	wrapper
+	rule ChainRule
+}
+
+// Chained returns a policy applying the policy and keeping, for each kept incremental backup, the backups it
+// depends on up to its full backup, so no kept incremental becomes unusable. Those backups are listed in the
+// chain tier, and the summary lists the chains it finds in Chains, flagging the broken ones.
+func Chained(rule ChainRule, policy Policy) Policy {
+	return &chainPolicy{wrapper: wrapper{policy}, rule: rule}
+}
+
+// Apply categorizes the files, then moves the parents of the kept files from deletion to the chain tier.
+func (p *chainPolicy) Apply(files []*File, current carbon.Carbon) *Summary {
+	summary := p.policy.Apply(files, current)
+	parents := p.rule.parents(files)
+
+	deleted := make(map[*File]bool)
+	for _, file := range summary.ForDelete {
+		deleted[file] = true
+	}
+
+	chain := &TierSummary{Name: TierChain}
+	for _, file := range sortedFiles(files) {
+		if deleted[file] {
+			continue
+		}
+		for parent, ok := parents[file]; ok && deleted[parent]; parent, ok = parents[parent] {
+			deleted[parent] = false
+			chain.Files = append(chain.Files, parent)
+			summary.addReason(parent, fmt.Sprintf("%s of %s", TierChain, baseName(file.Path)))
+		}
+	}
+
+	if len(chain.Files) > 0 {
+		summary.Tiers = append(summary.Tiers, chain)
+		forDelete := summary.ForDelete[:0]
+		for _, file := range summary.ForDelete {
+			if deleted[file] {
+				forDelete = append(forDelete, file)
+			}
+		}
+		summary.ForDelete = forDelete
+		summary.tally()
+	}
+	summary.Chains = p.rule.chains(files, parents)
+	return summary
+}
+
+// Validate checks the chain rule and the chained policy.
+func (p *chainPolicy) Validate() error {
+	if p.rule.Incremental == nil {
+		return fmt.Errorf("%w: no incremental rule", ErrInvalidPolicy)
+	}
+	return p.wrapper.Validate()
+}
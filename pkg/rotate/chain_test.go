@@ -0,0 +1,110 @@
+/*
+Copyright The Rotate Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rotate_test
+
+import (
+	"testing"
+
+	"github.com/golang-module/carbon"
+	"github.com/raniellyferreira/rotate-files/pkg/rotate"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChainedByTime(t *testing.T) {
+	today := carbon.CreateFromDateTime(2024, 6, 15, 10, 0, 0, "UTC")
+
+	// A full backup every Sunday, incrementals on the other days
+	var backups []*rotate.File
+	for i := 0; i < 15; i++ {
+		timestamp := today.SubDays(i)
+		kind := "incr"
+		if timestamp.IsSunday() {
+			kind = "full"
+		}
+		backups = append(backups, &rotate.File{Path: "db-" + timestamp.Format("Ymd") + "-" + kind + ".tar", Timestamp: timestamp})
+	}
+
+	rule, err := rotate.ParseChainRule(`-incr\.`, "")
+	assert.NoError(t, err)
+	scheme := &rotate.RotationScheme{KeepLast: 1, Timezone: "UTC"}
+	summary := rotate.Chained(rule, scheme).Apply(backups, today)
+
+	// The newest incremental needs every backup back to Sunday's full
+	assert.Equal(t, []string{
+		"db-20240614-incr.tar", "db-20240613-incr.tar", "db-20240612-incr.tar",
+		"db-20240611-incr.tar", "db-20240610-incr.tar", "db-20240609-full.tar",
+	}, paths(summary.Tier(rotate.TierChain).Files))
+	assert.Equal(t, "chain of db-20240615-incr.tar", summary.Reasons[backups[1]])
+	assert.Equal(t, 8, len(summary.ForDelete))
+	assert.Equal(t, len(backups), summary.GetTotalCategorized())
+
+	// The oldest incrementals lost their full backup
+	assert.Equal(t, 3, len(summary.Chains))
+	assert.True(t, summary.Chains[0].Broken)
+	assert.Equal(t, "db-20240602-full.tar", summary.Chains[1].Files[0].Path)
+	assert.Equal(t, 7, len(summary.Chains[1].Files))
+	assert.False(t, summary.Chains[2].Broken)
+}
+
+func TestChainedByParent(t *testing.T) {
+	today := carbon.CreateFromDateTime(2024, 6, 15, 10, 0, 0, "UTC")
+	backups := []*rotate.File{
+		{Path: "/backups/db-20240615-incr-from-20240610.tar", Timestamp: today},
+		{Path: "/backups/db-20240614-full.tar", Timestamp: today.SubDay()},
+		{Path: "/backups/db-20240612-incr-from-20240601.tar", Timestamp: today.SubDays(3)},
+		{Path: "/backups/db-20240610-full.tar", Timestamp: today.SubDays(5)},
+	}
+
+	rule, err := rotate.ParseChainRule(`-incr-`, `-from-(\d{8})`)
+	assert.NoError(t, err)
+	scheme := &rotate.RotationScheme{KeepLast: 1, Timezone: "UTC"}
+	summary := rotate.Chained(rule, scheme).Apply(backups, today)
+
+	// The newest incremental depends on the full backup it names, not on the one just before it
+	assert.Equal(t, []string{"/backups/db-20240610-full.tar"}, paths(summary.Tier(rotate.TierChain).Files))
+	assert.Equal(t, []string{"/backups/db-20240614-full.tar", "/backups/db-20240612-incr-from-20240601.tar"}, paths(summary.ForDelete))
+
+	assert.Equal(t, 2, len(summary.Chains))
+	assert.Equal(t, []string{"/backups/db-20240610-full.tar", "/backups/db-20240615-incr-from-20240610.tar"}, paths(summary.Chains[0].Files))
+	assert.True(t, summary.Chains[1].Broken)
+
+	_, err = rotate.ParseChainRule(`(`, "")
+	assert.Error(t, err)
+	assert.ErrorIs(t, rotate.Chained(rotate.ChainRule{}, scheme).(interface{ Validate() error }).Validate(), rotate.ErrInvalidPolicy)
+}
+
+func TestChainedByParentSkipsSiblings(t *testing.T) {
+	today := carbon.CreateFromDateTime(2024, 6, 15, 10, 0, 0, "UTC")
+	backups := []*rotate.File{
+		{Path: "/backups/db-20240613-incr-from-20240612.tar", Timestamp: today.SubDays(2)},
+		{Path: "/backups/db-20240612-incr-from-20240610.tar", Timestamp: today.SubDays(3)},
+		{Path: "/backups/db-20240611-incr-from-20240610.tar", Timestamp: today.SubDays(4)},
+		{Path: "/backups/db-20240610-full.tar", Timestamp: today.SubDays(5)},
+	}
+
+	rule, err := rotate.ParseChainRule(`-incr-`, `-from-(\d{8})`)
+	assert.NoError(t, err)
+	scheme := &rotate.RotationScheme{KeepLast: 1, Timezone: "UTC"}
+	summary := rotate.Chained(rule, scheme).Apply(backups, today)
+
+	// The older sibling naming the same full backup isn't its parent, while an incremental still
+	// depends on the incremental it names
+	assert.Equal(t, []string{
+		"/backups/db-20240612-incr-from-20240610.tar", "/backups/db-20240610-full.tar",
+	}, paths(summary.Tier(rotate.TierChain).Files))
+	assert.Equal(t, []string{"/backups/db-20240611-incr-from-20240610.tar"}, paths(summary.ForDelete))
+}
@@ -16,11 +16,27 @@ limitations under the License.
 
 package rotate
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+)
 
 var (
+	ErrNilPolicy         = errors.New("nil retention policy")
 	ErrNilRotationScheme = errors.New("nil rotation scheme")
 	ErrEmptyFileList     = errors.New("empty file list")
 	ErrSingleFile        = errors.New("single file")
 	ErrNilProvider       = errors.New("nil provider")
+	ErrInvalidTimezone   = errors.New("invalid timezone")
+	ErrInvalidWeekStart  = errors.New("invalid week start")
+	ErrInvalidMode       = errors.New("invalid rotation mode")
+	ErrInvalidSelection  = errors.New("invalid selection strategy")
+
+	ErrInvalidFiscalYearStart = errors.New("invalid fiscal year start month")
+	ErrInvalidTier            = errors.New("invalid tier")
+	ErrInvalidPolicy          = errors.New("invalid policy")
+	ErrDirectoryDelete        = errors.New("can't delete directory")
 )
+
+// errNilScheme is returned for a missing rotation scheme, which is also a missing policy.
+var errNilScheme = fmt.Errorf("%w: %w", ErrNilRotationScheme, ErrNilPolicy)
@@ -0,0 +1,84 @@
+/*
+Copyright The Rotate Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rotate
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/golang-module/carbon"
+)
+
+// TierExponential is the name of the tier kept by the exponential mode.
+const TierExponential = "exponential"
+
+// exponentialSlot returns the age slot of a timestamp: the slot 0 holds ages below the base, and
+// the slot n ages from base*2^(n-1) up to base*2^n. Future timestamps fall into the slot 0.
+func exponentialSlot(current carbon.Carbon, base time.Duration, c carbon.Carbon) int {
+	age := current.ToStdTime().Sub(c.ToStdTime())
+	slot := 0
+	for bound := base; bound > 0 && age >= bound; bound *= 2 {
+		slot++
+	}
+	return slot
+}
+
+// exponentialRule keeps every backup younger than the base, so the newest one is never lost, and the oldest
+// backup of each exponentially growing age slot after it (base to 2*base, 2*base to 4*base...), ignoring backups
+// older than the maximum age unless it is zero. Keeping the oldest lets a backup age through the slots, so
+// backups are thinned out as they get older instead of replaced.
+func (s *RotationScheme) exponentialRule(current carbon.Carbon) tierRule {
+	base := s.ExponentialBase
+	rule := tierRule{
+		name: TierExponential,
+		bucket: func(c carbon.Carbon) string {
+			slot := exponentialSlot(current, base, c)
+			if slot == 0 {
+				// Each timestamp of the slot 0 is a bucket of its own
+				return fmt.Sprintf("0 %d", c.ToStdTime().UnixNano())
+			}
+			return fmt.Sprint(slot)
+		},
+		strategy: SelectOldest,
+		limit:    -1,
+		reason: func(file *File) string {
+			slot := exponentialSlot(current, base, file.Timestamp)
+			if slot == 0 {
+				return fmt.Sprintf("age under %s", formatDuration(base))
+			}
+			return fmt.Sprintf("age %s-%s", formatDuration(base<<(slot-1)), formatDuration(base<<slot))
+		},
+	}
+	if s.ExponentialMaxAge > 0 {
+		rule.window = within(current, s.ExponentialMaxAge)
+	}
+	return rule
+}
+
+// formatDuration formats a duration in the largest of days, hours or minutes that divides it.
+func formatDuration(d time.Duration) string {
+	switch {
+	case d%Day == 0:
+		return fmt.Sprintf("%dd", d/Day)
+	case d%time.Hour == 0:
+		return fmt.Sprintf("%dh", d/time.Hour)
+	case d%time.Minute == 0:
+		return fmt.Sprintf("%dm", d/time.Minute)
+	default:
+		return d.String()
+	}
+}
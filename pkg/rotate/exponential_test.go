@@ -0,0 +1,91 @@
+/*
+Copyright The Rotate Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rotate_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/golang-module/carbon"
+	"github.com/raniellyferreira/rotate-files/pkg/rotate"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRotateFilesOfExponential(t *testing.T) {
+	today := carbon.CreateFromDateTime(2024, 6, 15, 10, 0, 0, "UTC")
+
+	// A snapshot every 15 minutes over the last ten days
+	var backups []*rotate.File
+	for i := 0; i < 10*24*4; i++ {
+		timestamp := today.SubMinutes(i * 15)
+		backups = append(backups, &rotate.File{Path: timestamp.ToDateTimeString(), Timestamp: timestamp})
+	}
+
+	scheme := rotate.RotationScheme{
+		Hourly:            24,
+		Mode:              rotate.ModeExponential,
+		ExponentialBase:   time.Hour,
+		ExponentialMaxAge: 7 * rotate.Day,
+		Timezone:          "UTC",
+	}
+	summary := rotate.RotateFilesOf(backups, &scheme, today)
+
+	// The GFS tiers are replaced by the age intervals, keeping every backup under the base
+	// and the oldest backup of each interval after it
+	assert.Nil(t, summary.Hourly)
+	exponential := summary.Tier(rotate.TierExponential).Files
+	assert.Equal(t, []string{
+		"2024-06-15 10:00:00", // under 1h
+		"2024-06-15 09:45:00", // under 1h
+		"2024-06-15 09:30:00", // under 1h
+		"2024-06-15 09:15:00", // under 1h
+		"2024-06-15 08:15:00", // 1h-2h
+		"2024-06-15 06:15:00", // 2h-4h
+		"2024-06-15 02:15:00", // 4h-8h
+		"2024-06-14 18:15:00", // 8h-16h
+		"2024-06-14 02:15:00", // 16h-32h
+		"2024-06-12 18:15:00", // 32h-64h
+		"2024-06-10 02:15:00", // 64h-128h
+		"2024-06-08 10:00:00", // 128h-256h, up to the maximum age
+	}, paths(exponential))
+
+	assert.Equal(t, "age under 1h", summary.Reasons[exponential[0]])
+	assert.Equal(t, "age under 1h", summary.Reasons[exponential[3]])
+	assert.Equal(t, "age 4h-8h", summary.Reasons[exponential[6]])
+	assert.Equal(t, "age 64h-128h", summary.Reasons[exponential[10]])
+	assert.Equal(t, len(backups)-12, len(summary.ForDelete))
+}
+
+func TestRotateFilesOfExponentialThinsOut(t *testing.T) {
+	today := carbon.CreateFromDateTime(2024, 6, 15, 10, 0, 0, "UTC")
+	scheme := rotate.RotationScheme{Mode: rotate.ModeExponential, ExponentialBase: time.Hour, Timezone: "UTC"}
+
+	// Run hourly for two weeks, adding a backup each run and deleting what the rotation drops
+	var backups []*rotate.File
+	for run := 0; run < 14*24; run++ {
+		now := today.AddHours(run)
+		backups = append(backups, &rotate.File{Path: now.ToDateTimeString(), Timestamp: now})
+
+		summary := rotate.RotateFilesOf(backups, &scheme, now)
+		backups = summary.Tier(rotate.TierExponential).Files
+
+		// One backup per age interval at most, and the newest one is always kept
+		assert.LessOrEqual(t, len(backups), 10)
+		assert.Equal(t, now.ToDateTimeString(), backups[0].Path)
+	}
+	assert.Equal(t, today.ToDateTimeString(), backups[len(backups)-1].Path)
+}
@@ -20,13 +20,27 @@ import (
 	"fmt"
 
 	"github.com/golang-module/carbon"
+	"github.com/raniellyferreira/rotate-files/pkg/providers"
 )
 
+// TimestampSourcePattern is the timestamp source of files whose timestamp was parsed from their path.
+const TimestampSourcePattern = "pattern"
+
 // File represents a backup file with its path, size, and timestamp.
+// TimestampSource tells where the timestamp came from (e.g. "modified" or "metadata:mtime").
+// Related lists the files kept or deleted together with this one, such as its checksum or manifest.
+// A Directory stands for a whole directory tree listed as one backup. Inodes holds the inodes of its
+// data when the provider knows them, telling hard links shared between backups apart.
+// InProgress marks a file still being written or uploaded.
 type File struct {
-	Path      string
-	Size      int64
-	Timestamp carbon.Carbon
+	Path            string
+	Size            int64
+	Timestamp       carbon.Carbon
+	TimestampSource string
+	Related         []*File
+	Directory       bool
+	Inodes          []providers.Inode
+	InProgress      bool
 }
 
 // String returns the string representation of the File, including path and timestamp.
@@ -34,50 +48,89 @@ func (b File) String() string {
 	return fmt.Sprintf("Path: %s, Timestamp: %s", b.Path, b.Timestamp)
 }
 
-// IsHourlyOf checks if the file is an hourly backup based on the provided date.
-func (b File) IsHourlyOf(date carbon.Carbon, prev *carbon.Carbon) bool {
+// TotalSize returns the size of the file and its related files.
+func (b File) TotalSize() int64 {
+	total := b.Size
+	for _, related := range b.Related {
+		total += related.Size
+	}
+	return total
+}
+
+// IsMinutelyOf checks if the file is a minutely backup based on the provided date, limit and interval in minutes.
+// The window spans the limit in intervals.
+func (b File) IsMinutelyOf(date carbon.Carbon, prev *carbon.Carbon, limit int, interval int) bool {
+	interval = max(interval, 1)
+	if prev != nil && minuteBucket(interval)(b.Timestamp) == minuteBucket(interval)(b.align(*prev)) {
+		return false
+	}
+	return b.Timestamp.DiffInMinutes(date) <= int64(limit*interval)
+}
+
+// IsHourlyOf checks if the file is an hourly backup based on the provided date and limit.
+// The window spans the limit in hours, and never less than a day.
+func (b File) IsHourlyOf(date carbon.Carbon, prev *carbon.Carbon, limit int) bool {
 	if b.IsSameHour(prev) {
 		return false
 	}
-	return b.Timestamp.DiffInHours(date) <= carbon.HoursPerDay
+	return b.Timestamp.DiffInHours(date) <= int64(max(limit, carbon.HoursPerDay))
 }
 
-// IsDailyOf checks if the file is a daily backup based on the provided date.
-func (b File) IsDailyOf(date carbon.Carbon, prev *carbon.Carbon) bool {
+// IsDailyOf checks if the file is a daily backup based on the provided date and limit.
+// The window spans the limit in days, and never less than a week.
+func (b File) IsDailyOf(date carbon.Carbon, prev *carbon.Carbon, limit int) bool {
 	if b.IsSameDay(prev) {
 		return false
 	}
-	diff := int(b.Timestamp.DiffInDays(date))
-	return diff >= 1 && diff <= carbon.DaysPerWeek
+	diff := b.Timestamp.DiffInDays(date)
+	return diff >= 1 && diff <= int64(max(limit, carbon.DaysPerWeek))
 }
 
 // IsWeeklyOf checks if the file is a weekly backup based on the provided date and limit.
+// Any backup can represent its calendar week; weeks start on the day set on the timestamps.
+// Like the other tiers, the week in progress is left to the shorter tiers.
 func (b File) IsWeeklyOf(date carbon.Carbon, prev *carbon.Carbon, limit int) bool {
-	if b.IsSameWeek(prev) {
+	if b.IsSameWeek(prev) || b.IsSameWeek(&date) {
 		return false
 	}
-	return b.Timestamp.DiffInWeeks(date) <= int64(limit) && b.Timestamp.IsSunday()
+	return b.Timestamp.DiffInWeeks(date) <= int64(limit)
 }
 
-// IsMonthlyOf checks if the file is a monthly backup based on the provided date.
-func (b File) IsMonthlyOf(date carbon.Carbon, prev *carbon.Carbon) bool {
+// IsMonthlyOf checks if the file is a monthly backup based on the provided date and limit.
+// The window spans one month more than the limit, since the last four weeks are left to the weekly tier,
+// and never less than 13 months.
+func (b File) IsMonthlyOf(date carbon.Carbon, prev *carbon.Carbon, limit int) bool {
 	if b.IsSameMonth(prev) {
 		return false
 	}
-	return b.Timestamp.DiffInMonths(date) <= 13 && b.Timestamp.DiffInWeeks(date) >= 4
+	return b.Timestamp.DiffInMonths(date) <= int64(max(limit+1, 13)) && b.Timestamp.DiffInWeeks(date) >= 4
+}
+
+// IsQuarterlyOf checks if the file is a quarterly backup based on the provided date, limit and fiscal year start month.
+// The quarter in progress is left to the other tiers, so the window spans one quarter more than the limit.
+func (b File) IsQuarterlyOf(date carbon.Carbon, prev *carbon.Carbon, limit int, fiscalYearStart int) bool {
+	if b.IsSameQuarter(prev, fiscalYearStart) || b.IsSameQuarter(&date, fiscalYearStart) {
+		return false
+	}
+	return b.Timestamp.DiffInMonths(date) <= int64((limit+1)*3)
 }
 
 // IsYearlyOf checks if the file is a yearly backup based on the provided date.
 func (b File) IsYearlyOf(date carbon.Carbon, prevBackup *carbon.Carbon) bool {
+	return b.IsFiscalYearlyOf(date, prevBackup, 1)
+}
+
+// IsFiscalYearlyOf checks if the file is a yearly backup based on the provided date, with years starting at the given month.
+func (b File) IsFiscalYearlyOf(date carbon.Carbon, prevBackup *carbon.Carbon, fiscalYearStart int) bool {
 	// Se houver um backup anterior no mesmo ano, não o consideramos anual
-	if b.IsSameYear(prevBackup) {
+	if b.IsSameFiscalYear(prevBackup, fiscalYearStart) {
 		return false
 	}
 
 	monthsDiff := b.Timestamp.DiffInMonths(date)
 
 	// Verificamos se o backup tem pelo menos 12 meses ou mais de 6 meses e é de um ano diferente
-	return monthsDiff >= 12 || (monthsDiff > 6 && !b.IsSameYear(&date))
+	return monthsDiff >= 12 || (monthsDiff > 6 && !b.IsSameFiscalYear(&date, fiscalYearStart))
 }
 
 // IsSameHour checks if the file has the same hour as the provided date.
@@ -85,7 +138,7 @@ func (b File) IsSameHour(compare *carbon.Carbon) bool {
 	if compare == nil {
 		return false
 	}
-	return b.Timestamp.IsSameHour(*compare)
+	return b.Timestamp.IsSameHour(b.align(*compare))
 }
 
 // IsSameDay checks if the file has the same day as the provided date.
@@ -93,7 +146,7 @@ func (b File) IsSameDay(compare *carbon.Carbon) bool {
 	if compare == nil {
 		return false
 	}
-	return b.Timestamp.IsSameDay(*compare)
+	return b.Timestamp.IsSameDay(b.align(*compare))
 }
 
 // IsSameWeek checks if the file has the same week as the provided date.
@@ -101,7 +154,8 @@ func (b File) IsSameWeek(compare *carbon.Carbon) bool {
 	if compare == nil {
 		return false
 	}
-	return b.Timestamp.Between(compare.StartOfWeek(), compare.EndOfWeek())
+	week := b.align(*compare)
+	return b.Timestamp.BetweenIncludedStart(week.StartOfWeek(), week.EndOfWeek())
 }
 
 // IsSameMonth checks if the file has the same month as the provided date.
@@ -109,7 +163,25 @@ func (b File) IsSameMonth(compare *carbon.Carbon) bool {
 	if compare == nil {
 		return false
 	}
-	return b.Timestamp.IsSameMonth(*compare)
+	return b.Timestamp.IsSameMonth(b.align(*compare))
+}
+
+// IsSameQuarter checks if the file has the same quarter as the provided date, with years starting at the given month.
+func (b File) IsSameQuarter(compare *carbon.Carbon, fiscalYearStart int) bool {
+	if compare == nil {
+		return false
+	}
+	bucket := quarterBucket(fiscalYearStart)
+	return bucket(b.Timestamp) == bucket(b.align(*compare))
+}
+
+// IsSameFiscalYear checks if the file has the same year as the provided date, with years starting at the given month.
+func (b File) IsSameFiscalYear(compare *carbon.Carbon, fiscalYearStart int) bool {
+	if compare == nil {
+		return false
+	}
+	bucket := yearBucket(fiscalYearStart)
+	return bucket(b.Timestamp) == bucket(b.align(*compare))
 }
 
 // IsSameYear checks if the file has the same year as the provided date.
@@ -117,14 +189,25 @@ func (b File) IsSameYear(compare *carbon.Carbon) bool {
 	if compare == nil {
 		return false
 	}
-	return b.Timestamp.IsSameYear(*compare)
+	return b.Timestamp.IsSameYear(b.align(*compare))
+}
+
+// align converts the date to the file's time zone, so calendar comparisons are made in a single zone.
+func (b File) align(date carbon.Carbon) carbon.Carbon {
+	if b.Timestamp.IsInvalid() {
+		return date
+	}
+	return date.SetLocation(b.Timestamp.ToStdTime().Location())
 }
 
 // Files is a slice of File pointers.
 type Files []*File
 
-// Less compares the elements at the given indexes.
+// Less compares the elements at the given indexes, newest first and by path for equal timestamps.
 func (b Files) Less(i, j int) bool {
+	if b[i].Timestamp.Eq(b[j].Timestamp) {
+		return b[i].Path < b[j].Path
+	}
 	return b[i].Timestamp.Gt(b[j].Timestamp)
 }
 
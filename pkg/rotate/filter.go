@@ -0,0 +1,221 @@
+/*
+Copyright The Rotate Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rotate
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// RegexpPrefix marks a filter pattern as a regular expression instead of a glob.
+const RegexpPrefix = "re:"
+
+// Filter selects the listed files taking part in rotation. A file takes part when it matches one of
+// the include patterns, if any, none of the exclude patterns, and is at least the minimum size.
+//
+// Patterns are globs such as "*.tmp", where "*" doesn't cross directories and "**" does, or regular
+// expressions prefixed with "re:", such as `re:\.(tmp|part)$`. Like timestamp patterns, they are
+// matched against the base name, or against the path when they contain "/". Globs must match the
+// whole name or a trailing part of the path, while regular expressions may match anywhere.
+type Filter struct {
+	include []filterPattern
+	exclude []filterPattern
+	minSize int64
+}
+
+// filterPattern is a compiled filter pattern and whether it is matched against the path.
+type filterPattern struct {
+	re       *regexp.Regexp
+	fullPath bool
+}
+
+// NewFilter compiles the include and exclude patterns of a filter.
+func NewFilter(include, exclude []string, minSize int64) (*Filter, error) {
+	if minSize < 0 {
+		return nil, fmt.Errorf("invalid minimum size %d", minSize)
+	}
+
+	f := &Filter{minSize: minSize}
+	for _, pattern := range include {
+		compiled, err := compileFilterPattern(pattern)
+		if err != nil {
+			return nil, err
+		}
+		f.include = append(f.include, compiled)
+	}
+	for _, pattern := range exclude {
+		compiled, err := compileFilterPattern(pattern)
+		if err != nil {
+			return nil, err
+		}
+		f.exclude = append(f.exclude, compiled)
+	}
+	return f, nil
+}
+
+// compileFilterPattern compiles a glob, or a regexp when prefixed with "re:".
+func compileFilterPattern(pattern string) (filterPattern, error) {
+	fullPath := strings.Contains(pattern, "/")
+	if expr, ok := strings.CutPrefix(pattern, RegexpPrefix); ok {
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return filterPattern{}, fmt.Errorf("invalid filter pattern %q: %w", pattern, err)
+		}
+		return filterPattern{re: re, fullPath: fullPath}, nil
+	}
+	if pattern == "" {
+		return filterPattern{}, fmt.Errorf("empty filter pattern")
+	}
+
+	var expr strings.Builder
+	if fullPath {
+		expr.WriteString(`(?:^|/)`)
+	} else {
+		expr.WriteString(`^`)
+	}
+	for i := 0; i < len(pattern); i++ {
+		switch c := pattern[i]; c {
+		case '*':
+			switch {
+			case strings.HasPrefix(pattern[i:], "**/"):
+				expr.WriteString(`(?:.*/)?`)
+				i += 2
+			case strings.HasPrefix(pattern[i:], "**"):
+				expr.WriteString(`.*`)
+				i++
+			default:
+				expr.WriteString(`[^/]*`)
+			}
+		case '?':
+			expr.WriteString(`[^/]`)
+		case '[':
+			end := strings.IndexByte(pattern[i+1:], ']')
+			if end < 0 {
+				return filterPattern{}, fmt.Errorf("invalid filter pattern %q: unterminated [", pattern)
+			}
+			class := pattern[i+1 : i+1+end]
+			if negated, ok := strings.CutPrefix(class, "!"); ok {
+				class = "^" + negated
+			}
+			expr.WriteString("[" + class + "]")
+			i += end + 1
+		default:
+			expr.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	expr.WriteString(`$`)
+
+	re, err := regexp.Compile(expr.String())
+	if err != nil {
+		return filterPattern{}, fmt.Errorf("invalid filter pattern %q: %w", pattern, err)
+	}
+	return filterPattern{re: re, fullPath: fullPath}, nil
+}
+
+// Match reports whether the file takes part in rotation.
+func (f *Filter) Match(file *File) bool {
+	if file.Size < f.minSize {
+		return false
+	}
+	if len(f.include) > 0 && !matchAny(f.include, file.Path) {
+		return false
+	}
+	return !matchAny(f.exclude, file.Path)
+}
+
+// matchAny reports whether one of the patterns matches the path.
+func matchAny(patterns []filterPattern, path string) bool {
+	for _, pattern := range patterns {
+		subject := path
+		if !pattern.fullPath {
+			subject = baseName(path)
+		}
+		if pattern.re.MatchString(subject) {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseFilterPatterns splits a comma-separated list of filter patterns, such as "*.tmp,*.part".
+// Commas within brackets, braces or parentheses, or escaped with a backslash, belong to the pattern,
+// so regexps such as "re:^db-\d{2,4}" and classes such as "[,;]" stay whole.
+func ParseFilterPatterns(value string) []string {
+	var patterns []string
+	add := func(pattern string) {
+		if pattern = strings.TrimSpace(pattern); pattern != "" {
+			patterns = append(patterns, pattern)
+		}
+	}
+
+	depth, start, class := 0, 0, false
+	for i := 0; i < len(value); i++ {
+		switch c := value[i]; {
+		case c == '\\':
+			i++
+		case class:
+			// Within a character class only its closing bracket is special
+			class = c != ']'
+		case c == '[':
+			class = true
+		case c == '{' || c == '(':
+			depth++
+		case (c == '}' || c == ')') && depth > 0:
+			depth--
+		case c == ',':
+			if depth == 0 {
+				add(value[start:i])
+				start = i + 1
+			}
+		}
+	}
+	add(value[start:])
+	return patterns
+}
+
+// sizeUnits maps the size suffixes ParseSize accepts to their multiple of bytes, in powers of 1024
+// like the sizes printed in the summary.
+var sizeUnits = map[string]int64{
+	"": 1, "b": 1,
+	"k": 1 << 10, "kb": 1 << 10, "kib": 1 << 10,
+	"m": 1 << 20, "mb": 1 << 20, "mib": 1 << 20,
+	"g": 1 << 30, "gb": 1 << 30, "gib": 1 << 30,
+	"t": 1 << 40, "tb": 1 << 40, "tib": 1 << 40,
+}
+
+// sizeValue matches a size such as "512", "10K" or "1.5 GB".
+var sizeValue = regexp.MustCompile(`^(\d+(?:\.\d*)?|\.\d+)\s*([a-zA-Z]*)$`)
+
+// ParseSize parses a size in bytes, optionally followed by a unit in powers of 1024: "K", "M", "G" or "T",
+// with or without "B" or "iB", e.g. "10K" or "1.5GB".
+func ParseSize(value string) (int64, error) {
+	match := sizeValue.FindStringSubmatch(strings.TrimSpace(value))
+	if match == nil {
+		return 0, fmt.Errorf("invalid size %q", value)
+	}
+	unit, ok := sizeUnits[strings.ToLower(match[2])]
+	if !ok {
+		return 0, fmt.Errorf("invalid size %q: unknown unit %q", value, match[2])
+	}
+	n, err := strconv.ParseFloat(match[1], 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid size %q", value)
+	}
+	return int64(n * float64(unit)), nil
+}
@@ -0,0 +1,94 @@
+/*
+Copyright The Rotate Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rotate_test
+
+import (
+	"testing"
+
+	"github.com/raniellyferreira/rotate-files/pkg/rotate"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFilter(t *testing.T) {
+	filter, err := rotate.NewFilter(nil, rotate.ParseFilterPatterns("*.tmp, *.part,README*,re:\\.sha\\d+$,daily/**/*.log"), 0)
+	assert.NoError(t, err)
+
+	for path, match := range map[string]bool{
+		"s3://bucket/db-20240615.sql.gz":        true,
+		"s3://bucket/db-20240615.sql.gz.part":   false,
+		"/backups/db.tmp":                       false,
+		"/backups/db.tmp.gz":                    true,
+		"/backups/README.md":                    false,
+		"/backups/docs/README":                  false,
+		"/backups/db.tar.zst.sha256":            false,
+		"/backups/daily/2024/06/db.log":         false,
+		"/backups/weekly/2024/06/db.log":        true,
+		"/backups/tmp/db-20240615.sql.gz":       true,
+		"gs://bucket/backups/daily/db.log.gz":   true,
+		"blob://account/container/daily/db.log": false,
+	} {
+		assert.Equal(t, match, filter.Match(&rotate.File{Path: path}), path)
+	}
+
+	// Include patterns restrict rotation to the matching files, on top of the minimum size
+	filter, err = rotate.NewFilter([]string{"db-????????.sql.gz", "re:^users-"}, []string{"db-2023*"}, 1024)
+	assert.NoError(t, err)
+	assert.True(t, filter.Match(&rotate.File{Path: "/backups/db-20240615.sql.gz", Size: 2048}))
+	assert.True(t, filter.Match(&rotate.File{Path: "/backups/users-1.sql", Size: 1024}))
+	assert.False(t, filter.Match(&rotate.File{Path: "/backups/db-20240615.sql.gz", Size: 10}))
+	assert.False(t, filter.Match(&rotate.File{Path: "/backups/db-20230615.sql.gz", Size: 2048}))
+	assert.False(t, filter.Match(&rotate.File{Path: "/backups/orders-20240615.sql.gz", Size: 2048}))
+
+	for _, pattern := range []string{"", "re:(", "db-[0-9"} {
+		_, err = rotate.NewFilter([]string{pattern}, nil, 0)
+		assert.Error(t, err, pattern)
+	}
+	_, err = rotate.NewFilter(nil, nil, -1)
+	assert.Error(t, err)
+}
+
+func TestParseFilterPatterns(t *testing.T) {
+	assert.Equal(t, []string{"*.tmp", "*.part"}, rotate.ParseFilterPatterns("*.tmp, *.part,"))
+
+	// Commas within a regexp quantifier, class or group, or escaped, don't split the pattern
+	patterns := rotate.ParseFilterPatterns(`re:^db-\d{2,4}\.sql,re:[,;]$,re:^(full|incr),*.log\,v2,README*`)
+	assert.Equal(t, []string{`re:^db-\d{2,4}\.sql`, `re:[,;]$`, `re:^(full|incr)`, `*.log\,v2`, `README*`}, patterns)
+
+	filter, err := rotate.NewFilter(patterns[:1], nil, 0)
+	assert.NoError(t, err)
+	assert.True(t, filter.Match(&rotate.File{Path: "/backups/db-2024.sql"}))
+	assert.False(t, filter.Match(&rotate.File{Path: "/backups/db-2.sql"}))
+}
+
+func TestParseSize(t *testing.T) {
+	for value, size := range map[string]int64{
+		"512":    512,
+		"10K":    10 << 10,
+		"1.5 GB": 3 << 29,
+		"2MiB":   2 << 20,
+		"1t":     1 << 40,
+	} {
+		parsed, err := rotate.ParseSize(value)
+		assert.NoError(t, err, value)
+		assert.Equal(t, size, parsed, value)
+	}
+
+	for _, value := range []string{"", "K", "-1", "10X"} {
+		_, err := rotate.ParseSize(value)
+		assert.Error(t, err, value)
+	}
+}
@@ -0,0 +1,112 @@
+/*
+Copyright The Rotate Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rotate
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// GroupByStemName is the ParseGroupBy value grouping files by the name before their timestamp.
+const GroupByStemName = "stem"
+
+// stemTimestamp matches the start of the timestamp in a file name: the first run of at least four digits.
+var stemTimestamp = regexp.MustCompile(`\d{4,}`)
+
+// GroupByStem returns the family of a file: its base name up to the timestamp, without trailing
+// separators, so "orders-2024-06-15.sql.gz" and "orders_20240616.sql.gz" are both "orders".
+// Names without a timestamp are their own family.
+func GroupByStem(file *File) string {
+	name := baseName(file.Path)
+	if loc := stemTimestamp.FindStringIndex(name); loc != nil {
+		name = name[:loc[0]]
+	}
+	return strings.TrimRight(name, "-_. ")
+}
+
+// GroupByRegexp returns a key function grouping files by the first capture group of the regexp,
+// or by the whole match when it has no group. Like timestamp patterns, the regexp is matched
+// against the base name, or against the path when it contains "/". Unmatched files share the empty key.
+func GroupByRegexp(re *regexp.Regexp) func(*File) string {
+	fullPath := strings.Contains(re.String(), "/")
+	return func(file *File) string {
+		subject := file.Path
+		if !fullPath {
+			subject = baseName(file.Path)
+		}
+		match := re.FindStringSubmatch(subject)
+		switch {
+		case match == nil:
+			return ""
+		case len(match) > 1:
+			return match[1]
+		default:
+			return match[0]
+		}
+	}
+}
+
+// ParseGroupBy returns the key function for "stem" or for a regexp such as `^([a-z]+)-`.
+func ParseGroupBy(value string) (func(*File) string, error) {
+	if value == GroupByStemName {
+		return GroupByStem, nil
+	}
+	re, err := regexp.Compile(value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid group regexp %q: %w", value, err)
+	}
+	return GroupByRegexp(re), nil
+}
+
+// RootGroup is the group of the files directly under the root in GroupByDirectory.
+const RootGroup = "."
+
+// GroupByDirectory returns a key function grouping files by the first depth directories of their
+// path below the root, so each subdirectory or sub-prefix is rotated as its own backup set.
+// The root is a local path or a s3://, gs:// or blob:// URL, whatever alias the provider accepts;
+// files less deep than the depth are grouped by the directories they have, and files directly under the root
+// form their own group, named RootGroup.
+func GroupByDirectory(root string, depth int) func(*File) string {
+	root = strings.TrimSuffix(withoutScheme(root), "/")
+	return func(file *File) string {
+		rel := withoutScheme(file.Path)
+		if root != "." {
+			rel = strings.TrimPrefix(strings.TrimPrefix(rel, root), "/")
+		}
+
+		dirs := strings.Split(rel, "/")
+		dirs = dirs[:len(dirs)-1]
+		if len(dirs) > depth {
+			dirs = dirs[:depth]
+		}
+		if len(dirs) == 0 {
+			return RootGroup
+		}
+		return strings.Join(dirs, "/")
+	}
+}
+
+// withoutScheme returns the path of a cloud URL without its scheme, since providers may report
+// another alias than the user's (gs:// for gc://), or the cleaned slash-separated local path.
+func withoutScheme(path string) string {
+	if _, rest, found := strings.Cut(path, "://"); found {
+		return rest
+	}
+	return filepath.ToSlash(filepath.Clean(path))
+}
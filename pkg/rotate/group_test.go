@@ -0,0 +1,162 @@
+/*
+Copyright The Rotate Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rotate_test
+
+import (
+	"testing"
+
+	"github.com/golang-module/carbon"
+	"github.com/raniellyferreira/rotate-files/pkg/rotate"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGroupByStem(t *testing.T) {
+	for path, family := range map[string]string{
+		"s3://bucket/dumps/orders-2024-06-15.sql.gz": "orders",
+		"/backups/users_20240615T1000.sql.gz":        "users",
+		"db2-orders.20240615.tar":                    "db2-orders",
+		"1718445600.tar":                             "",
+		"notes.txt":                                  "notes.txt",
+	} {
+		assert.Equal(t, family, rotate.GroupByStem(&rotate.File{Path: path}), path)
+	}
+}
+
+func TestParseGroupBy(t *testing.T) {
+	key, err := rotate.ParseGroupBy(`^([a-z]+)-`)
+	assert.NoError(t, err)
+	assert.Equal(t, "orders", key(&rotate.File{Path: "/dumps/orders-2024.sql"}))
+	assert.Equal(t, "", key(&rotate.File{Path: "/dumps/2024.sql"}))
+
+	// Patterns with a slash match the path
+	key, err = rotate.ParseGroupBy(`/dumps/(\w+)/`)
+	assert.NoError(t, err)
+	assert.Equal(t, "hostA", key(&rotate.File{Path: "s3://bucket/dumps/hostA/2024.sql"}))
+
+	key, err = rotate.ParseGroupBy(rotate.GroupByStemName)
+	assert.NoError(t, err)
+	assert.Equal(t, "users", key(&rotate.File{Path: "users-2024.sql"}))
+
+	_, err = rotate.ParseGroupBy(`(`)
+	assert.Error(t, err)
+}
+
+func TestRotateFilesOfPerFamily(t *testing.T) {
+	today := carbon.CreateFromDateTime(2024, 6, 15, 10, 0, 0, "UTC")
+
+	// The busy orders database is dumped every hour, the users database once a day
+	var backups []*rotate.File
+	for i := 0; i < 10*24; i++ {
+		timestamp := today.SubHours(i)
+		backups = append(backups, &rotate.File{Path: "orders-" + timestamp.ToShortDateTimeString(), Timestamp: timestamp, Size: 10})
+	}
+	for i := 0; i < 10; i++ {
+		timestamp := today.SubDays(i).SubMinutes(30)
+		backups = append(backups, &rotate.File{Path: "users-" + timestamp.ToShortDateTimeString(), Timestamp: timestamp, Size: 1})
+	}
+
+	scheme := &rotate.RotationScheme{Daily: 7, Timezone: "UTC"}
+	summary := rotate.PerGroup(rotate.GroupByStem, scheme).Apply(backups, today)
+
+	// Each family keeps its own dailies instead of sharing one timeline
+	assert.Equal(t, 2, len(summary.Groups))
+	orders, users := summary.Groups[0], summary.Groups[1]
+	assert.Equal(t, "orders", orders.Group)
+	assert.Equal(t, 7, len(orders.Daily))
+	assert.Equal(t, int64(70), orders.SizeTotalDaily)
+	assert.Equal(t, "users", users.Group)
+	assert.Equal(t, 7, len(users.Daily))
+	assert.Equal(t, int64(7), users.SizeTotalDaily)
+
+	assert.Equal(t, 14, len(summary.Daily))
+	assert.Equal(t, len(backups)-14, len(summary.ForDelete))
+	assert.Equal(t, orders.SizeTotalForDelete+users.SizeTotalForDelete, summary.SizeTotalForDelete)
+}
+
+func TestGroupByDirectory(t *testing.T) {
+	for _, tc := range []struct {
+		root  string
+		depth int
+		path  string
+		group string
+	}{
+		{"/backups", 1, "/backups/hostA/db/2024.sql", "hostA"},
+		{"/backups/", 2, "/backups/hostA/db/2024.sql", "hostA/db"},
+		{"/backups", 3, "/backups/hostA/2024.sql", "hostA"},
+		{"/backups", 1, "/backups/2024.sql", rotate.RootGroup},
+		{".", 1, "2024.sql", rotate.RootGroup},
+		{"s3://bucket", 1, "s3://bucket/2024.sql", rotate.RootGroup},
+		{"./backups", 1, "backups/hostB/2024.sql", "hostB"},
+		{".", 1, "hostB/2024.sql", "hostB"},
+		{"s3://bucket/backups/", 1, "s3://bucket/backups/hostA/2024.sql", "hostA"},
+		{"s3://bucket", 1, "s3://bucket/hostA/2024.sql", "hostA"},
+		{"gc://bucket/backups", 1, "gs://bucket/backups/hostA/2024.sql", "hostA"},
+		{"azure://account/container/backups", 1, "blob://account/container/backups/hostA/2024.sql", "hostA"},
+	} {
+		key := rotate.GroupByDirectory(tc.root, tc.depth)
+		assert.Equal(t, tc.group, key(&rotate.File{Path: tc.path}), tc.root+" "+tc.path)
+	}
+}
+
+func TestRotateFilesOfPerDirectory(t *testing.T) {
+	today := carbon.CreateFromDateTime(2024, 6, 15, 10, 0, 0, "UTC")
+
+	var backups []*rotate.File
+	for _, host := range []string{"hostA", "hostB"} {
+		for i := 0; i < 5; i++ {
+			timestamp := today.SubDays(i)
+			backups = append(backups, &rotate.File{Path: "s3://bucket/backups/" + host + "/" + timestamp.ToDateString(), Timestamp: timestamp})
+		}
+	}
+
+	scheme := &rotate.RotationScheme{KeepLast: 2, Timezone: "UTC"}
+	summary := rotate.PerGroup(rotate.GroupByDirectory("s3://bucket/backups", 1), scheme).Apply(backups, today)
+
+	assert.Equal(t, 2, len(summary.Groups))
+	for _, group := range summary.Groups {
+		assert.Equal(t, []string{
+			"s3://bucket/backups/" + group.Group + "/2024-06-15",
+			"s3://bucket/backups/" + group.Group + "/2024-06-14",
+		}, paths(group.Tier(rotate.TierLast).Files))
+	}
+	assert.Equal(t, 6, len(summary.ForDelete))
+}
+
+func TestRotateFilesOfPerDirectoryWithRootFiles(t *testing.T) {
+	today := carbon.CreateFromDateTime(2024, 6, 15, 10, 0, 0, "UTC")
+
+	var backups []*rotate.File
+	for _, dir := range []string{"", "hostA/", "hostB/"} {
+		for i := 0; i < 4; i++ {
+			timestamp := today.SubDays(i)
+			backups = append(backups, &rotate.File{Path: "/backups/" + dir + timestamp.ToDateString(), Timestamp: timestamp})
+		}
+	}
+
+	scheme := &rotate.RotationScheme{KeepLast: 2, Timezone: "UTC"}
+	summary := rotate.PerGroup(rotate.GroupByDirectory("/backups", 1), scheme).Apply(backups, today)
+
+	// The files directly under the path are a set of their own, next to each subdirectory
+	assert.Equal(t, 3, len(summary.Groups))
+	assert.Equal(t, rotate.RootGroup, summary.Groups[0].Group)
+	assert.Equal(t, []string{"/backups/2024-06-15", "/backups/2024-06-14"}, paths(summary.Groups[0].Tier(rotate.TierLast).Files))
+	assert.Equal(t, "hostA", summary.Groups[1].Group)
+	assert.Equal(t, []string{"/backups/hostA/2024-06-15", "/backups/hostA/2024-06-14"}, paths(summary.Groups[1].Tier(rotate.TierLast).Files))
+	assert.Equal(t, "hostB", summary.Groups[2].Group)
+	assert.Equal(t, []string{"/backups/hostB/2024-06-15", "/backups/hostB/2024-06-14"}, paths(summary.Groups[2].Tier(rotate.TierLast).Files))
+	assert.Equal(t, 6, len(summary.ForDelete))
+}
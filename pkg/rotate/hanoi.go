@@ -0,0 +1,62 @@
+/*
+Copyright The Rotate Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rotate
+
+import (
+	"fmt"
+	"math/bits"
+
+	"github.com/golang-module/carbon"
+)
+
+// TierHanoi is the name of the tier kept by the Hanoi mode.
+const TierHanoi = "hanoi"
+
+// hanoiLevel returns the level of the Tower of Hanoi rotation a timestamp belongs to. Slots are
+// numbered from the Unix epoch, so the level of a backup never changes between runs: the slot n
+// uses the level given by the trailing zero bits of n, capped at the last level.
+func hanoiLevel(c carbon.Carbon, s *RotationScheme) int {
+	slot := uint64(wallPeriod(c, s.HanoiInterval))
+	return min(bits.TrailingZeros64(slot), s.HanoiLevels-1)
+}
+
+// hanoiRule keeps the newest backup of each level, as a Tower of Hanoi rotation reuses the
+// media of a level each time the level comes back: the level 0 every other slot, the level 1
+// every fourth slot and so on, with the last level taking the remaining slots.
+// The files must be sorted newest first.
+func (s *RotationScheme) hanoiRule(files Files) tierRule {
+	kept := make(map[*File]bool)
+	levels := make(map[int]bool)
+	for _, file := range files {
+		level := hanoiLevel(file.Timestamp, s)
+		if !levels[level] {
+			levels[level] = true
+			kept[file] = true
+		}
+	}
+
+	return tierRule{
+		name: TierHanoi,
+		window: func(file *File) bool {
+			return kept[file]
+		},
+		limit: -1,
+		reason: func(file *File) string {
+			return fmt.Sprintf("level %d", hanoiLevel(file.Timestamp, s))
+		},
+	}
+}
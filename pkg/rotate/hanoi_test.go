@@ -0,0 +1,85 @@
+/*
+Copyright The Rotate Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rotate_test
+
+import (
+	"math/bits"
+	"testing"
+
+	"github.com/golang-module/carbon"
+	"github.com/raniellyferreira/rotate-files/pkg/rotate"
+	"github.com/stretchr/testify/assert"
+)
+
+var hanoiScheme = rotate.RotationScheme{
+	Daily:         7,
+	Mode:          rotate.ModeHanoi,
+	HanoiLevels:   4,
+	HanoiInterval: rotate.Day,
+	Timezone:      "UTC",
+}
+
+func TestRotateFilesOfHanoi(t *testing.T) {
+	today := carbon.CreateFromDateTime(2024, 6, 15, 10, 0, 0, "UTC")
+
+	var backups []*rotate.File
+	for i := 0; i < 40; i++ {
+		timestamp := today.SubDays(i)
+		backups = append(backups, &rotate.File{Path: timestamp.ToDateString(), Timestamp: timestamp})
+	}
+
+	summary := rotate.RotateFilesOf(backups, &hanoiScheme, today)
+
+	// 2024-06-15 is the day 19889 since the epoch: level 0 on odd days, level 1 on days
+	// divisible by 2 only, level 2 by 4 only and the last level on days divisible by 8.
+	assert.Nil(t, summary.Daily)
+	hanoi := summary.Tier(rotate.TierHanoi).Files
+	assert.Equal(t, []string{"2024-06-15", "2024-06-14", "2024-06-12", "2024-06-10"}, paths(hanoi))
+	assert.Equal(t, "level 0", summary.Reasons[hanoi[0]])
+	assert.Equal(t, "level 3", summary.Reasons[hanoi[1]])
+	assert.Equal(t, "level 1", summary.Reasons[hanoi[2]])
+	assert.Equal(t, "level 2", summary.Reasons[hanoi[3]])
+	assert.Equal(t, 40-4, len(summary.ForDelete))
+}
+
+func TestRotateFilesOfHanoiRuns(t *testing.T) {
+	today := carbon.CreateFromDateTime(2024, 6, 15, 10, 0, 0, "UTC")
+
+	// Run daily, adding a backup each run and deleting what the rotation drops
+	var backups []*rotate.File
+	for run := 0; run < 64; run++ {
+		now := today.AddDays(run)
+		latest := &rotate.File{Path: now.ToDateString(), Timestamp: now}
+		backups = append(backups, latest)
+
+		summary := rotate.RotateFilesOf(backups, &hanoiScheme, now)
+
+		// Only the backup of the level coming back is replaced
+		assert.LessOrEqual(t, len(summary.ForDelete), 1)
+		for _, file := range summary.ForDelete {
+			assert.Equal(t, hanoiLevel(latest), hanoiLevel(file))
+		}
+		backups = summary.Tier(rotate.TierHanoi).Files
+		assert.LessOrEqual(t, len(backups), hanoiScheme.HanoiLevels)
+	}
+}
+
+// hanoiLevel returns the level of a daily backup in a 4 levels rotation.
+func hanoiLevel(file *rotate.File) int {
+	day := file.Timestamp.Timestamp() / 86400
+	return min(bits.TrailingZeros64(uint64(day)), 3)
+}
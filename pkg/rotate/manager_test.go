@@ -18,11 +18,16 @@ package rotate_test
 
 import (
 	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/golang-module/carbon"
+	"github.com/raniellyferreira/rotate-files/pkg/files"
 	"github.com/raniellyferreira/rotate-files/pkg/providers"
 	"github.com/raniellyferreira/rotate-files/pkg/rotate"
+	"github.com/stretchr/testify/assert"
 )
 
 // DummyProvider is a mock implementation of the Provider interface for testing.
@@ -104,6 +109,51 @@ func TestRotationManager_SingleFileError(t *testing.T) {
 	}
 }
 
+func TestRotationManager_NothingLeftToRotate(t *testing.T) {
+	files := []*providers.FileInfo{
+		{Path: "db-2024.sql", Size: 100, Timestamp: carbon.Now().SubHours(1)},
+		{Path: "db-2023.sql", Size: 200, Timestamp: carbon.Now().SubDays(1)},
+	}
+	provider := &DummyProvider{files: files, err: nil}
+	manager := rotate.NewRotationManager(provider, &rotate.RotationScheme{Hourly: 1}, "dummy/path")
+	pattern, err := rotate.NewTimestampPattern("backup-%Y%m%d")
+	if err != nil {
+		t.Fatal(err)
+	}
+	manager.SetTimestampPattern(pattern)
+
+	// The files the pattern doesn't match are still reported
+	summary, err := manager.RotateFiles()
+	if !errors.Is(err, rotate.ErrEmptyFileList) {
+		t.Errorf("expected ErrEmptyFileList, got %v", err)
+	}
+	if summary == nil || len(summary.Unmatched) != 2 {
+		t.Errorf("expected the unmatched files in the summary, got %v", summary)
+	}
+}
+
+func TestRotationManager_UploadsNotListed(t *testing.T) {
+	files := []*providers.FileInfo{
+		{Path: "file1", Size: 100, Timestamp: carbon.Now().SubHours(1)},
+		{Path: "file2", Size: 200, Timestamp: carbon.Now().SubDays(1)},
+	}
+	denied := fmt.Errorf("%w: access denied", providers.ErrUploadsNotListed)
+	provider := &DummyProvider{files: files, err: denied}
+	manager := rotate.NewRotationManager(provider, &rotate.RotationScheme{Hourly: 1, Daily: 1}, "dummy/path")
+
+	// The files are still rotated, the error being left to the caller as a warning
+	summary, err := manager.RotateFiles()
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(summary.Hourly) != 1 || len(summary.Daily) != 1 {
+		t.Errorf("unexpected number of rotated files")
+	}
+	if len(summary.Warnings) != 1 || !errors.Is(summary.Warnings[0], providers.ErrUploadsNotListed) {
+		t.Errorf("expected ErrUploadsNotListed in the warnings, got %v", summary.Warnings)
+	}
+}
+
 func TestRotationManager_NilRotationScheme(t *testing.T) {
 	files := []*providers.FileInfo{
 		{Path: "file1", Size: 100, Timestamp: carbon.Now().SubHours(1)},
@@ -158,3 +208,249 @@ func TestRotationManager_RemoveFileError(t *testing.T) {
 		t.Errorf("expected 'delete error', got %v", err)
 	}
 }
+
+func TestRotationManager_TimestampPattern(t *testing.T) {
+	files := []*providers.FileInfo{
+		{Path: "db-20240101.sql", Size: 100, Timestamp: carbon.Now()},
+		{Path: "db-20240102.sql", Size: 100, Timestamp: carbon.Now()},
+		{Path: "db-20240103.sql", Size: 100, Timestamp: carbon.Now()},
+		{Path: "README.md", Size: 10, Timestamp: carbon.Now()},
+	}
+	provider := &DummyProvider{files: files, err: nil}
+	scheme := &rotate.RotationScheme{Yearly: -1}
+
+	pattern, err := rotate.NewTimestampPattern("db-%Y%m%d.sql")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	manager := rotate.NewRotationManager(provider, scheme, "dummy/path")
+	manager.SetTimestampPattern(pattern)
+
+	summary, err := manager.RotateFiles()
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if len(summary.Unmatched) != 1 || summary.Unmatched[0].Path != "README.md" {
+		t.Errorf("expected README.md to be unmatched, got %v", summary.Unmatched)
+	}
+
+	for _, file := range summary.ForDelete {
+		if file.Path == "README.md" {
+			t.Errorf("unmatched file must not be deleted")
+		}
+	}
+
+	if summary.GetTotalCategorized() == 0 || summary.Yearly[0].Timestamp.Year() != 2024 {
+		t.Errorf("expected timestamps parsed from the file names")
+	}
+}
+
+func TestRotationManager_Filter(t *testing.T) {
+	files := []*providers.FileInfo{
+		{Path: "backups/db-1.sql.gz", Size: 100, Timestamp: carbon.Now().SubDays(1)},
+		{Path: "backups/db-2.sql.gz", Size: 100, Timestamp: carbon.Now().SubDays(2)},
+		{Path: "backups/db-3.sql.gz", Size: 100, Timestamp: carbon.Now().SubDays(30)},
+		{Path: "backups/db-4.sql.gz.part", Size: 50, Timestamp: carbon.Now().SubDays(40)},
+		{Path: "backups/db-5.sql.gz", Size: 0, Timestamp: carbon.Now().SubDays(50)},
+		{Path: "backups/README.md", Size: 10, Timestamp: carbon.Now().SubDays(60)},
+	}
+	provider := &DummyProvider{files: files, err: nil}
+	scheme := &rotate.RotationScheme{KeepLast: 1}
+
+	filter, err := rotate.NewFilter([]string{"*.sql.gz*"}, []string{"*.part"}, 1)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	manager := rotate.NewRotationManager(provider, scheme, "dummy/path")
+	manager.SetFilter(filter)
+
+	summary, err := manager.RotateFiles()
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if len(summary.Excluded) != 3 {
+		t.Errorf("expected the partial, empty and README files to be excluded, got %v", summary.Excluded)
+	}
+
+	if len(summary.ForDelete) != 2 {
+		t.Errorf("expected only the filtered files to be deleted, got %v", summary.ForDelete)
+	}
+}
+
+func TestRotationManager_InProgress(t *testing.T) {
+	files := []*providers.FileInfo{
+		{Path: "backups/db-1.sql.gz", Size: 40, Timestamp: carbon.Now(), InProgress: true},
+		{Path: "backups/db-2.sql.gz", Size: 100, Timestamp: carbon.Now().SubDays(1)},
+		{Path: "backups/db-3.sql.gz", Size: 100, Timestamp: carbon.Now().SubDays(2)},
+	}
+	provider := &DummyProvider{files: files, err: nil}
+	scheme := &rotate.RotationScheme{KeepLast: 1}
+	manager := rotate.NewRotationManager(provider, scheme, "dummy/path")
+
+	summary, err := manager.RotateFiles()
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if len(summary.InProgress) != 1 || summary.InProgress[0].Path != "backups/db-1.sql.gz" {
+		t.Errorf("expected the partial dump to be in progress, got %v", summary.InProgress)
+	}
+
+	// The partial dump doesn't push out the newest complete one
+	kept := summary.Tier(rotate.TierLast).Files
+	if len(kept) != 1 || kept[0].Path != "backups/db-2.sql.gz" {
+		t.Errorf("expected the newest complete dump to be kept, got %v", kept)
+	}
+	if len(summary.ForDelete) != 1 || summary.ForDelete[0].Path != "backups/db-3.sql.gz" {
+		t.Errorf("expected only the oldest dump to be deleted, got %v", summary.ForDelete)
+	}
+}
+
+// DirectoryProvider is a DummyProvider that can delete directories, recording them.
+type DirectoryProvider struct {
+	DummyProvider
+	deleted []string
+}
+
+func (d *DirectoryProvider) DeleteDirectory(path string) error {
+	d.deleted = append(d.deleted, path)
+	return nil
+}
+
+func TestRotationManager_RemoveDirectory(t *testing.T) {
+	backup := &rotate.File{Path: "s3://bucket/backups/2024-10-01T00:00", Directory: true}
+
+	manager := rotate.NewRotationManager(&DummyProvider{}, &rotate.RotationScheme{}, "s3://bucket/backups/")
+	if err := manager.RemoveFileSet(backup, nil); !errors.Is(err, rotate.ErrDirectoryDelete) {
+		t.Errorf("expected ErrDirectoryDelete without provider support, got %v", err)
+	}
+
+	provider := &DirectoryProvider{}
+	manager = rotate.NewRotationManager(provider, &rotate.RotationScheme{}, "s3://bucket/backups/")
+	if err := manager.RemoveFileSet(backup, nil); err != nil {
+		t.Errorf("expected no error, got %v", err)
+	}
+
+	for _, path := range []string{"s3://bucket/backups", "s3://bucket/backups-old/2024", "s3://bucket/other"} {
+		if err := manager.RemoveFileSet(&rotate.File{Path: path, Directory: true}, nil); !errors.Is(err, rotate.ErrDirectoryDelete) {
+			t.Errorf("expected ErrDirectoryDelete outside the path for %s, got %v", path, err)
+		}
+	}
+
+	manager = rotate.NewRotationManager(provider, &rotate.RotationScheme{}, "/backups")
+	for _, path := range []string{"/backups", "/backups/../etc", "/"} {
+		if err := manager.RemoveFileSet(&rotate.File{Path: path, Directory: true}, nil); !errors.Is(err, rotate.ErrDirectoryDelete) {
+			t.Errorf("expected ErrDirectoryDelete outside the path for %s, got %v", path, err)
+		}
+	}
+
+	if len(provider.deleted) != 1 || provider.deleted[0] != backup.Path {
+		t.Errorf("expected only %s to be deleted, got %v", backup.Path, provider.deleted)
+	}
+}
+
+func TestRotationManager_RemoveFileSetReportsDeletedFiles(t *testing.T) {
+	backup := &rotate.File{Path: "/backups/db.tar.zst", Related: []*rotate.File{
+		{Path: "/backups/db.tar.zst.sha256"},
+		{Path: "/backups/db.manifest.json"},
+	}}
+
+	var reported []string
+	deleting := func(file *rotate.File) { reported = append(reported, file.Path) }
+
+	manager := rotate.NewRotationManager(&DummyProvider{}, &rotate.RotationScheme{}, "/backups")
+	assert.NoError(t, manager.RemoveFileSet(backup, deleting))
+	assert.Equal(t, []string{"/backups/db.tar.zst.sha256", "/backups/db.manifest.json", "/backups/db.tar.zst"}, reported)
+
+	// The deletion stops at the first error, and so do the reports
+	reported = nil
+	manager = rotate.NewRotationManager(&DummyProvider{err: errors.New("access denied")}, &rotate.RotationScheme{}, "/backups")
+	if err := manager.RemoveFileSet(backup, deleting); err == nil {
+		t.Errorf("expected the provider error")
+	}
+	assert.Equal(t, []string{"/backups/db.tar.zst.sha256"}, reported)
+}
+
+func TestRotationManager_RemoveEmptyDirectory(t *testing.T) {
+	manager := rotate.NewRotationManager(&DummyProvider{}, &rotate.RotationScheme{}, "/backups")
+	if _, err := manager.EmptyDirectories(nil); !errors.Is(err, rotate.ErrDirectoryDelete) {
+		t.Errorf("expected ErrDirectoryDelete without provider support, got %v", err)
+	}
+
+	root := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(root, "2023", "07"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	manager = rotate.NewRotationManager(files.NewLocalProvider(), &rotate.RotationScheme{}, root)
+	for _, path := range []string{root, filepath.Dir(root), root + "-old"} {
+		if err := manager.RemoveEmptyDirectory(path); !errors.Is(err, rotate.ErrDirectoryDelete) {
+			t.Errorf("expected ErrDirectoryDelete outside the path for %s, got %v", path, err)
+		}
+	}
+
+	backup := &rotate.File{Path: filepath.Join(root, "2023", "07", "a.tar"), Related: []*rotate.File{{Path: filepath.Join(root, "2023", "07", "a.tar.sha256")}}}
+	dirs, err := manager.EmptyDirectories([]*rotate.File{backup})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	for _, dir := range dirs {
+		if err := manager.RemoveEmptyDirectory(dir); err != nil {
+			t.Errorf("expected no error, got %v", err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(root, "2023")); !os.IsNotExist(err) {
+		t.Errorf("expected the empty directories to be removed, got %v", err)
+	}
+	if _, err := os.Stat(root); err != nil {
+		t.Errorf("expected the path to be kept, got %v", err)
+	}
+}
+
+func TestRotationManager_InvalidTimezone(t *testing.T) {
+	files := []*providers.FileInfo{
+		{Path: "file1", Size: 100, Timestamp: carbon.Now().SubHours(1)},
+		{Path: "file2", Size: 200, Timestamp: carbon.Now().SubDays(1)},
+	}
+	provider := &DummyProvider{files: files, err: nil}
+	scheme := &rotate.RotationScheme{Hourly: 1, Timezone: "Mars/Olympus_Mons"}
+	manager := rotate.NewRotationManager(provider, scheme, "dummy/path")
+
+	_, err := manager.RotateFiles()
+	if err == nil || !errors.Is(err, rotate.ErrInvalidTimezone) {
+		t.Errorf("expected ErrInvalidTimezone, got %v", err)
+	}
+}
+
+func TestRotationManager_InvalidMode(t *testing.T) {
+	files := []*providers.FileInfo{
+		{Path: "file1", Size: 100, Timestamp: carbon.Now().SubHours(1)},
+		{Path: "file2", Size: 200, Timestamp: carbon.Now().SubDays(1)},
+	}
+	provider := &DummyProvider{files: files, err: nil}
+	scheme := &rotate.RotationScheme{Hourly: 1, Mode: "random"}
+	manager := rotate.NewRotationManager(provider, scheme, "dummy/path")
+
+	_, err := manager.RotateFiles()
+	if err == nil || !errors.Is(err, rotate.ErrInvalidMode) {
+		t.Errorf("expected ErrInvalidMode, got %v", err)
+	}
+}
+
+func TestRotationManager_InvalidFiscalYearStart(t *testing.T) {
+	files := []*providers.FileInfo{
+		{Path: "file1", Size: 100, Timestamp: carbon.Now().SubHours(1)},
+		{Path: "file2", Size: 200, Timestamp: carbon.Now().SubDays(1)},
+	}
+	provider := &DummyProvider{files: files, err: nil}
+	scheme := &rotate.RotationScheme{Yearly: 1, FiscalYearStart: 13}
+	manager := rotate.NewRotationManager(provider, scheme, "dummy/path")
+
+	_, err := manager.RotateFiles()
+	if err == nil || !errors.Is(err, rotate.ErrInvalidFiscalYearStart) {
+		t.Errorf("expected ErrInvalidFiscalYearStart, got %v", err)
+	}
+}
@@ -0,0 +1,202 @@
+/*
+Copyright The Rotate Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rotate
+
+import (
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/golang-module/carbon"
+)
+
+// Policy decides which files to keep. Apply categorizes the files relative to the current time,
+// listing in ForDelete those to delete. A *RotationScheme is a Policy.
+type Policy interface {
+	Apply(files []*File, current carbon.Carbon) *Summary
+}
+
+// validator is implemented by policies that can check their configuration before rotating.
+type validator interface {
+	Validate() error
+}
+
+// locator is implemented by policies evaluating the calendar in a time zone.
+type locator interface {
+	Location() (*time.Location, error)
+}
+
+// validatePolicy checks a policy applied by another one, which must not be nil.
+func validatePolicy(policy Policy) error {
+	if policy == nil {
+		return ErrNilPolicy
+	}
+	if v, ok := policy.(validator); ok {
+		return v.Validate()
+	}
+	return nil
+}
+
+// policyLocation returns the time zone of a policy, the local zone when it has none.
+func policyLocation(policy Policy) (*time.Location, error) {
+	if l, ok := policy.(locator); ok {
+		return l.Location()
+	}
+	return time.Local, nil
+}
+
+// wrapper is embedded by the policies applying another policy, validating it and taking its time zone.
+type wrapper struct {
+	policy Policy
+}
+
+// Validate checks the wrapped policy.
+func (w wrapper) Validate() error {
+	return validatePolicy(w.policy)
+}
+
+// Location returns the time zone of the wrapped policy, the local zone when it has none.
+func (w wrapper) Location() (*time.Location, error) {
+	return policyLocation(w.policy)
+}
+
+// unionPolicy keeps a file if any of its policies keeps it.
+type unionPolicy struct {
+	policies []Policy
+}
+
+// Union returns a policy keeping a file if any of the policies keeps it. The summary lists the
+// tiers of every policy, and a file is deleted only when every policy deletes it.
+func Union(policies ...Policy) Policy {
+	return &unionPolicy{policies: policies}
+}
+
+// Apply categorizes the files with every policy and merges the summaries.
+func (u *unionPolicy) Apply(files []*File, current carbon.Carbon) *Summary {
+	summary := &Summary{Reasons: make(map[*File]string)}
+	deletes := make(map[*File]int)
+	for _, policy := range u.policies {
+		s := policy.Apply(files, current)
+		summary.merge(s)
+		for _, file := range s.ForDelete {
+			deletes[file]++
+		}
+	}
+
+	for _, file := range sortedFiles(files) {
+		if len(u.policies) > 0 && deletes[file] == len(u.policies) {
+			summary.ForDelete = append(summary.ForDelete, file)
+		}
+	}
+	summary.tally()
+	return summary
+}
+
+// Validate checks every policy, requiring at least one.
+func (u *unionPolicy) Validate() error {
+	if len(u.policies) == 0 {
+		return ErrNilPolicy
+	}
+	for _, policy := range u.policies {
+		if err := validatePolicy(policy); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Location returns the time zone of the first policy that has one, the local zone otherwise.
+func (u *unionPolicy) Location() (*time.Location, error) {
+	for _, policy := range u.policies {
+		if l, ok := policy.(locator); ok {
+			return l.Location()
+		}
+	}
+	return time.Local, nil
+}
+
+// groupPolicy applies a policy to each group of files independently.
+type groupPolicy struct {
+	wrapper
+	key func(*File) string
+}
+
+// PerGroup returns a policy partitioning the files by key and applying the policy to each group
+// on its own, so a busy group doesn't crowd out the others. A nil key puts every file in one group. The summary merges the groups, which
+// are also listed by key in Groups.
+func PerGroup(key func(*File) string, policy Policy) Policy {
+	return &groupPolicy{wrapper: wrapper{policy}, key: key}
+}
+
+// Apply categorizes each group of files and merges the summaries.
+func (g *groupPolicy) Apply(files []*File, current carbon.Carbon) *Summary {
+	groups := make(map[string][]*File)
+	var keys []string
+	for _, file := range files {
+		var key string
+		if g.key != nil {
+			key = g.key(file)
+		}
+		if _, ok := groups[key]; !ok {
+			keys = append(keys, key)
+		}
+		groups[key] = append(groups[key], file)
+	}
+	sort.Strings(keys)
+
+	summary := &Summary{Reasons: make(map[*File]string)}
+	for _, key := range keys {
+		s := g.policy.Apply(groups[key], current)
+		s.Group = key
+		summary.Groups = append(summary.Groups, s)
+		summary.merge(s)
+		summary.ForDelete = append(summary.ForDelete, s.ForDelete...)
+	}
+	summary.tally()
+	return summary
+}
+
+// merge adds the tiers, reasons and chains of another summary, joining the files of tiers with the same name.
+func (s *Summary) merge(other *Summary) {
+	for _, tier := range other.Tiers {
+		existing := s.Tier(tier.Name)
+		if existing == nil {
+			existing = &TierSummary{Name: tier.Name}
+			s.Tiers = append(s.Tiers, existing)
+		}
+		for _, file := range tier.Files {
+			if !slices.Contains(existing.Files, file) {
+				existing.Files = append(existing.Files, file)
+			}
+		}
+	}
+	for file, reason := range other.Reasons {
+		s.addReason(file, reason)
+	}
+	for _, chain := range other.Chains {
+		if !slices.ContainsFunc(s.Chains, func(c *Chain) bool { return c.Files[0] == chain.Files[0] }) {
+			s.Chains = append(s.Chains, chain)
+		}
+	}
+}
+
+// sortedFiles returns a copy of the files sorted newest first.
+func sortedFiles(files []*File) []*File {
+	sorted := append(Files(nil), files...)
+	sort.Sort(sorted)
+	return sorted
+}
@@ -0,0 +1,221 @@
+/*
+Copyright The Rotate Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rotate
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// PolicyPresets are the named policies ParsePolicy accepts in place of, or along with, key=value pairs.
+var PolicyPresets = map[string]string{
+	"gfs-standard":  "hourly=24,daily=7,weekly=4,monthly=12,yearly=forever",
+	"compliance-7y": "daily=30,monthly=84,yearly=7",
+}
+
+// forever is the count keeping every period of a tier.
+const forever = "forever"
+
+// PolicySyntaxError reports the token of a policy expression that couldn't be parsed.
+type PolicySyntaxError struct {
+	Offset int
+	Token  string
+	Msg    string
+}
+
+// Error returns the message with the offset, in bytes, of the offending token.
+func (e *PolicySyntaxError) Error() string {
+	return fmt.Sprintf("%s at offset %d (%q): %s", ErrInvalidPolicy, e.Offset, e.Token, e.Msg)
+}
+
+// Unwrap returns ErrInvalidPolicy.
+func (e *PolicySyntaxError) Unwrap() error {
+	return ErrInvalidPolicy
+}
+
+// ParsePolicy parses a comma-separated policy expression such as
+// "hourly=24,daily=30,weekly=8,monthly=12,yearly=forever,keep-last=3" into a rotation scheme.
+//
+// The keys are the tiers (minutely, hourly, daily, weekly, monthly, quarterly and yearly, counting
+// periods or "forever"), minutely-interval, fiscal-year-start, keep-last, keep-within and
+// keep-within-daily, and durations such as "6h=8" for user-defined tiers. A token without a value
+// names a preset of PolicyPresets, e.g. "gfs-standard,keep-last=3"; later tokens override earlier ones.
+func ParsePolicy(expr string) (*RotationScheme, error) {
+	scheme := &RotationScheme{}
+	if err := scheme.parsePolicy(expr, true); err != nil {
+		return nil, err
+	}
+	if err := validateTiers(scheme.Tiers); err != nil {
+		return nil, err
+	}
+	return scheme, nil
+}
+
+// parsePolicy sets the scheme's retention from the expression, expanding presets when allowed.
+func (s *RotationScheme) parsePolicy(expr string, presets bool) error {
+	offset := 0
+	for _, raw := range strings.Split(expr, ",") {
+		token := strings.TrimSpace(raw)
+		start := offset + strings.Index(raw, token)
+		offset += len(raw) + 1
+
+		if token == "" {
+			return &PolicySyntaxError{Offset: start, Token: raw, Msg: "empty token"}
+		}
+
+		key, value, found := strings.Cut(token, "=")
+		key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+		if !found {
+			preset, ok := PolicyPresets[key]
+			if !ok || !presets {
+				return &PolicySyntaxError{Offset: start, Token: token, Msg: "unknown preset, expected key=value"}
+			}
+			if err := s.parsePolicy(preset, false); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if msg := s.setPolicyValue(key, value); msg != "" {
+			return &PolicySyntaxError{Offset: start, Token: token, Msg: msg}
+		}
+	}
+	return nil
+}
+
+// setPolicyValue sets one key of the policy, returning why the value is rejected, if it is.
+func (s *RotationScheme) setPolicyValue(key, value string) string {
+	count := func(field *int) string {
+		if value == forever {
+			*field = -1
+			return ""
+		}
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			return fmt.Sprintf("invalid count %q, expected a number or %s", value, forever)
+		}
+		*field = n
+		return ""
+	}
+	duration := func(field *time.Duration) string {
+		d, err := ParseDuration(value)
+		if err != nil {
+			return err.Error()
+		}
+		*field = d
+		return ""
+	}
+
+	switch key {
+	case TierMinutely:
+		return count(&s.Minutely)
+	case TierHourly:
+		return count(&s.Hourly)
+	case TierDaily:
+		return count(&s.Daily)
+	case TierWeekly:
+		return count(&s.Weekly)
+	case TierMonthly:
+		return count(&s.Monthly)
+	case TierQuarterly:
+		return count(&s.Quarterly)
+	case TierYearly:
+		return count(&s.Yearly)
+	case "keep-last":
+		if value == forever {
+			return "keep-last needs a number"
+		}
+		return count(&s.KeepLast)
+	case "keep-within":
+		return duration(&s.KeepWithin)
+	case "keep-within-daily":
+		return duration(&s.KeepWithinDaily)
+	case "minutely-interval":
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 {
+			return fmt.Sprintf("invalid interval %q, expected minutes", value)
+		}
+		s.MinutelyInterval = n
+		return ""
+	case "fiscal-year-start":
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 || n > 12 {
+			return fmt.Sprintf("invalid month %q, expected 1 to 12", value)
+		}
+		s.FiscalYearStart = n
+		return ""
+	}
+
+	interval, err := ParseDuration(key)
+	if err != nil {
+		return fmt.Sprintf("unknown key %q", key)
+	}
+	tier := Tier{Name: key, Interval: interval}
+	if msg := count(&tier.Count); msg != "" {
+		return msg
+	}
+	for i := range s.Tiers {
+		if s.Tiers[i].Name == key {
+			s.Tiers[i] = tier
+			return ""
+		}
+	}
+	s.Tiers = append(s.Tiers, tier)
+	return ""
+}
+
+// String returns the scheme's retention in the canonical form of the policy language, omitting
+// unset keys. User-defined tiers are written by their interval.
+func (s *RotationScheme) String() string {
+	var tokens []string
+	count := func(key string, n int) {
+		switch {
+		case n < 0:
+			tokens = append(tokens, key+"="+forever)
+		case n > 0:
+			tokens = append(tokens, key+"="+strconv.Itoa(n))
+		}
+	}
+	duration := func(key string, d time.Duration) {
+		if d > 0 {
+			tokens = append(tokens, key+"="+formatDuration(d))
+		}
+	}
+
+	count(TierMinutely, s.Minutely)
+	if s.Minutely != 0 {
+		count("minutely-interval", s.MinutelyInterval)
+	}
+	count(TierHourly, s.Hourly)
+	count(TierDaily, s.Daily)
+	count(TierWeekly, s.Weekly)
+	count(TierMonthly, s.Monthly)
+	count(TierQuarterly, s.Quarterly)
+	count(TierYearly, s.Yearly)
+	if s.FiscalYearStart > 1 {
+		count("fiscal-year-start", s.FiscalYearStart)
+	}
+	for _, tier := range s.Tiers {
+		count(formatDuration(tier.Interval), tier.Count)
+	}
+	count("keep-last", s.KeepLast)
+	duration("keep-within", s.KeepWithin)
+	duration("keep-within-daily", s.KeepWithinDaily)
+	return strings.Join(tokens, ",")
+}
@@ -0,0 +1,90 @@
+/*
+Copyright The Rotate Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rotate_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/raniellyferreira/rotate-files/pkg/rotate"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParsePolicy(t *testing.T) {
+	scheme, err := rotate.ParsePolicy("hourly=24, daily=30,weekly=8,monthly=12,yearly=forever,keep-last=3")
+	assert.NoError(t, err)
+	assert.Equal(t, &rotate.RotationScheme{
+		Hourly: 24, Daily: 30, Weekly: 8, Monthly: 12, Yearly: -1, KeepLast: 3,
+	}, scheme)
+	assert.Equal(t, "hourly=24,daily=30,weekly=8,monthly=12,yearly=forever,keep-last=3", scheme.String())
+
+	scheme, err = rotate.ParsePolicy("keep-within=48h,6h=8,keep-within-daily=30d,fiscal-year-start=4,quarterly=28")
+	assert.NoError(t, err)
+	assert.Equal(t, 48*time.Hour, scheme.KeepWithin)
+	assert.Equal(t, []rotate.Tier{{Name: "6h", Interval: 6 * time.Hour, Count: 8}}, scheme.Tiers)
+	assert.Equal(t, "quarterly=28,fiscal-year-start=4,6h=8,keep-within=2d,keep-within-daily=30d", scheme.String())
+}
+
+func TestParsePolicyPresets(t *testing.T) {
+	scheme, err := rotate.ParsePolicy("gfs-standard")
+	assert.NoError(t, err)
+	assert.Equal(t, "hourly=24,daily=7,weekly=4,monthly=12,yearly=forever", scheme.String())
+
+	// Later tokens override the preset
+	scheme, err = rotate.ParsePolicy("compliance-7y, daily=0, keep-last=3")
+	assert.NoError(t, err)
+	assert.Equal(t, "monthly=84,yearly=7,keep-last=3", scheme.String())
+}
+
+func TestParsePolicyRoundTrip(t *testing.T) {
+	for _, expr := range []string{
+		"minutely=12,minutely-interval=5,hourly=forever",
+		"daily=7,1d=14,30d=forever,keep-within=36h",
+		"gfs-standard,keep-last=1",
+	} {
+		scheme, err := rotate.ParsePolicy(expr)
+		assert.NoError(t, err, expr)
+
+		canonical := scheme.String()
+		reparsed, err := rotate.ParsePolicy(canonical)
+		assert.NoError(t, err, canonical)
+		assert.Equal(t, canonical, reparsed.String())
+	}
+}
+
+func TestParsePolicyErrors(t *testing.T) {
+	for expr, offset := range map[string]int{
+		"daily=x":                 0,
+		"hourly=24, daily=-1":     11,
+		"hourly=24,,daily=1":      10,
+		"hourly=24,  someday=3":   12,
+		"gfs-standard,gfs-custom": 13,
+		"keep-within=2 days":      0,
+		"keep-last=forever":       0,
+		"fiscal-year-start=13":    0,
+		"daily=1,":                8,
+	} {
+		_, err := rotate.ParsePolicy(expr)
+		assert.ErrorIs(t, err, rotate.ErrInvalidPolicy, expr)
+
+		var syntaxErr *rotate.PolicySyntaxError
+		if assert.True(t, errors.As(err, &syntaxErr), expr) {
+			assert.Equal(t, offset, syntaxErr.Offset, expr)
+		}
+	}
+}
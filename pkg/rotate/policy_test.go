@@ -0,0 +1,136 @@
+/*
+Copyright The Rotate Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rotate_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/golang-module/carbon"
+	"github.com/raniellyferreira/rotate-files/pkg/providers"
+	"github.com/raniellyferreira/rotate-files/pkg/rotate"
+	"github.com/stretchr/testify/assert"
+)
+
+// largePolicy keeps the files of at least a minimum size.
+type largePolicy struct {
+	min int64
+}
+
+func (p largePolicy) Apply(files []*rotate.File, current carbon.Carbon) *rotate.Summary {
+	summary := &rotate.Summary{}
+	large := &rotate.TierSummary{Name: "large"}
+	for _, file := range files {
+		if file.Size >= p.min {
+			large.Files = append(large.Files, file)
+		} else {
+			summary.ForDelete = append(summary.ForDelete, file)
+		}
+	}
+	summary.Tiers = append(summary.Tiers, large)
+	return summary
+}
+
+func TestRotationManager_CustomPolicy(t *testing.T) {
+	files := []*providers.FileInfo{
+		{Path: "small", Size: 100, Timestamp: carbon.Now().SubHours(1)},
+		{Path: "large", Size: 200, Timestamp: carbon.Now().SubDays(1)},
+	}
+	provider := &DummyProvider{files: files, err: nil}
+	manager := rotate.NewRotationManager(provider, largePolicy{min: 150}, "dummy/path")
+
+	summary, err := manager.RotateFiles()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"large"}, paths(summary.Tier("large").Files))
+	assert.Equal(t, []string{"small"}, paths(summary.ForDelete))
+}
+
+func TestRotationManager_NilPolicy(t *testing.T) {
+	files := []*providers.FileInfo{
+		{Path: "file1", Size: 100, Timestamp: carbon.Now().SubHours(1)},
+		{Path: "file2", Size: 200, Timestamp: carbon.Now().SubDays(1)},
+	}
+	provider := &DummyProvider{files: files, err: nil}
+
+	// A missing scheme is a missing policy too
+	var scheme *rotate.RotationScheme
+	for _, policy := range []rotate.Policy{nil, scheme} {
+		manager := rotate.NewRotationManager(provider, policy, "dummy/path")
+		_, err := manager.RotateFiles()
+		assert.ErrorIs(t, err, rotate.ErrNilPolicy)
+		assert.ErrorIs(t, err, rotate.ErrNilRotationScheme)
+		assert.Equal(t, "nil rotation scheme", rotate.ErrNilRotationScheme.Error())
+	}
+
+	for _, policy := range []rotate.Policy{rotate.Union(), rotate.Union(nil), rotate.PerGroup(nil, nil), rotate.Linked(rotate.LinkByStem, nil)} {
+		manager := rotate.NewRotationManager(provider, policy, "dummy/path")
+		_, err := manager.RotateFiles()
+		assert.ErrorIs(t, err, rotate.ErrNilPolicy)
+		assert.NotErrorIs(t, err, rotate.ErrNilRotationScheme)
+	}
+}
+
+func TestUnion(t *testing.T) {
+	today := carbon.CreateFromDateTime(2024, 6, 15, 10, 0, 0, "UTC")
+
+	backups := []*rotate.File{
+		{Path: "/today", Timestamp: today.SubHours(1), Size: 1},
+		{Path: "/old-large", Timestamp: today.SubDays(90), Size: 500},
+		{Path: "/old-small", Timestamp: today.SubDays(91), Size: 1},
+	}
+
+	scheme := &rotate.RotationScheme{Hourly: 24, Timezone: "UTC"}
+	summary := rotate.Union(scheme, largePolicy{min: 100}).Apply(backups, today)
+
+	assert.Equal(t, []string{"/today"}, paths(summary.Hourly))
+	assert.Equal(t, []string{"/old-large"}, paths(summary.Tier("large").Files))
+	assert.Equal(t, []string{"/old-small"}, paths(summary.ForDelete))
+	assert.Equal(t, int64(1), summary.SizeTotalForDelete)
+}
+
+func TestPerGroup(t *testing.T) {
+	today := carbon.CreateFromDateTime(2024, 6, 15, 10, 0, 0, "UTC")
+
+	// The orders database is dumped hourly, the users database daily
+	var backups []*rotate.File
+	for i := 0; i < 72; i++ {
+		timestamp := today.SubHours(i)
+		backups = append(backups, &rotate.File{Path: "/orders-" + timestamp.ToDateTimeString(), Timestamp: timestamp})
+	}
+	for i := 0; i < 3; i++ {
+		timestamp := today.SubDays(i)
+		backups = append(backups, &rotate.File{Path: "/users-" + timestamp.ToDateTimeString(), Timestamp: timestamp})
+	}
+
+	database := func(file *rotate.File) string {
+		name, _, _ := strings.Cut(strings.TrimPrefix(file.Path, "/"), "-")
+		return name
+	}
+	scheme := &rotate.RotationScheme{KeepLast: 2, Timezone: "UTC"}
+	summary := rotate.PerGroup(database, scheme).Apply(backups, today)
+
+	// Each database keeps its own last backups
+	assert.Equal(t, 2, len(summary.Groups))
+	assert.Equal(t, "orders", summary.Groups[0].Group)
+	assert.Equal(t, 2, len(summary.Groups[0].Tier(rotate.TierLast).Files))
+	assert.Equal(t, 70, len(summary.Groups[0].ForDelete))
+	assert.Equal(t, "users", summary.Groups[1].Group)
+	assert.Equal(t, 1, len(summary.Groups[1].ForDelete))
+
+	assert.Equal(t, 4, len(summary.Tier(rotate.TierLast).Files))
+	assert.Equal(t, 71, len(summary.ForDelete))
+}
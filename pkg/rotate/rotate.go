@@ -17,37 +17,63 @@ limitations under the License.
 package rotate
 
 import (
-	"sort"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
 
 	"github.com/golang-module/carbon"
 	"github.com/raniellyferreira/rotate-files/pkg/providers"
 )
 
 type RotationManager struct {
-	provider       providers.Provider
-	rotationScheme *RotationScheme
-	path           string
+	provider         providers.Provider
+	policy           Policy
+	path             string
+	timestampPattern *TimestampPattern
+	filter           *Filter
+	listOptions      providers.ListOptions
 }
 
-// NewRotationManager creates a new RotationManager with a rotation scheme.
-func NewRotationManager(provider providers.Provider, scheme *RotationScheme, path string) *RotationManager {
+// NewRotationManager creates a new RotationManager with a retention policy, such as a *RotationScheme.
+func NewRotationManager(provider providers.Provider, policy Policy, path string) *RotationManager {
 	return &RotationManager{
-		provider:       provider,
-		rotationScheme: scheme,
-		path:           path,
+		provider: provider,
+		policy:   policy,
+		path:     path,
 	}
 }
 
+// SetTimestampPattern makes the manager derive file timestamps from their paths instead of the provider metadata.
+func (r *RotationManager) SetTimestampPattern(pattern *TimestampPattern) {
+	r.timestampPattern = pattern
+}
+
+// SetFilter makes the manager leave out of rotation the listed files the filter doesn't match.
+func (r *RotationManager) SetFilter(filter *Filter) {
+	r.filter = filter
+}
+
+// SetListOptions sets the options passed to the provider when listing files, for providers implementing
+// providers.OptionsLister.
+func (r *RotationManager) SetListOptions(opts providers.ListOptions) {
+	r.listOptions = opts
+}
+
 // Validate checks if the rotation manager is ready to rotate files.
 func (r *RotationManager) Validate(fileList []*File) error {
-	if r.rotationScheme == nil {
-		return ErrNilRotationScheme
+	if r.policy == nil {
+		return errNilScheme
 	}
 
 	if r.provider == nil {
 		return ErrNilProvider
 	}
 
+	if err := validatePolicy(r.policy); err != nil {
+		return err
+	}
+
 	if len(fileList) == 0 {
 		return ErrEmptyFileList
 	}
@@ -59,22 +85,92 @@ func (r *RotationManager) Validate(fileList []*File) error {
 	return nil
 }
 
-// ListFiles retrieves a list of files from the specified path.
+// ListFiles retrieves a list of files from the specified path. When the uploads in progress can't be
+// listed, the files are returned along with providers.ErrUploadsNotListed.
 func (r *RotationManager) ListFiles(path string) ([]*File, error) {
-	infos, err := r.provider.ListFiles(path)
-	if err != nil {
+	infos, err := r.listInfos(path)
+	if err != nil && !errors.Is(err, providers.ErrUploadsNotListed) {
 		return nil, err
 	}
 
 	fileList := make([]*File, len(infos))
 	for i, info := range infos {
 		fileList[i] = &File{
-			Path:      info.Path,
-			Size:      info.Size,
-			Timestamp: info.Timestamp,
+			Path:            info.Path,
+			Size:            info.Size,
+			Timestamp:       info.Timestamp,
+			TimestampSource: info.TimestampSource,
+			Directory:       info.Directory,
+			Inodes:          info.Inodes,
+			InProgress:      info.InProgress,
+		}
+	}
+	return fileList, err
+}
+
+// listInfos lists the files with the manager's options, when the provider supports them.
+func (r *RotationManager) listInfos(path string) ([]*providers.FileInfo, error) {
+	if lister, ok := r.provider.(providers.OptionsLister); ok {
+		return lister.ListFilesWithOptions(path, r.listOptions)
+	}
+	return r.provider.ListFiles(path)
+}
+
+// FilterFiles splits off the files the filter doesn't match, which are never rotated nor deleted.
+func (r *RotationManager) FilterFiles(fileList []*File) ([]*File, []*File) {
+	if r.filter == nil {
+		return fileList, nil
+	}
+
+	var matched, excluded []*File
+	for _, file := range fileList {
+		if r.filter.Match(file) {
+			matched = append(matched, file)
+		} else {
+			excluded = append(excluded, file)
+		}
+	}
+	return matched, excluded
+}
+
+// SkipInProgress splits off the files still being written or uploaded, so a partial backup neither counts as
+// the newest of its period nor gets deleted.
+func (r *RotationManager) SkipInProgress(fileList []*File) ([]*File, []*File) {
+	var complete, inProgress []*File
+	for _, file := range fileList {
+		if file.InProgress {
+			inProgress = append(inProgress, file)
+		} else {
+			complete = append(complete, file)
 		}
 	}
-	return fileList, nil
+	return complete, inProgress
+}
+
+// ParseTimestamps fills the file timestamps from the timestamp pattern, splitting off the files that don't match it.
+// Dates found in the paths are interpreted in the policy's time zone, when it has one.
+func (r *RotationManager) ParseTimestamps(fileList []*File) ([]*File, []*File) {
+	if r.timestampPattern == nil {
+		return fileList, nil
+	}
+
+	loc, err := policyLocation(r.policy)
+	if err != nil {
+		loc = time.Local
+	}
+
+	var matched, unmatched []*File
+	for _, file := range fileList {
+		timestamp, ok := r.timestampPattern.ParseInLocation(file.Path, loc)
+		if !ok {
+			unmatched = append(unmatched, file)
+			continue
+		}
+		file.Timestamp = timestamp
+		file.TimestampSource = TimestampSourcePattern
+		matched = append(matched, file)
+	}
+	return matched, unmatched
 }
 
 // RemoveFile deletes a file from the filesystem using the specified full path.
@@ -82,86 +178,147 @@ func (r *RotationManager) RemoveFile(fullPath string) error {
 	return r.provider.Delete(fullPath)
 }
 
-// RotateFiles retrieves the files and categorizes them based on the rotation scheme and the current time.
+// RemoveFileSet deletes a file and its related files. The related files go first, so a set that
+// fails halfway keeps the file representing it and is rotated again on the next run. The deleting
+// function, when not nil, is called before each file is deleted, so callers can report progress.
+func (r *RotationManager) RemoveFileSet(file *File, deleting func(*File)) error {
+	for _, related := range file.Related {
+		if err := r.removeReported(related, deleting); err != nil {
+			return err
+		}
+	}
+	return r.removeReported(file, deleting)
+}
+
+// removeReported reports a file or a directory entry to the deleting function, then deletes it.
+func (r *RotationManager) removeReported(file *File, deleting func(*File)) error {
+	if deleting != nil {
+		deleting(file)
+	}
+	return r.removeEntry(file)
+}
+
+// removeEntry deletes a file, or the tree of a directory entry. Directories are only deleted by providers
+// supporting it, and only below the rotated path, so a bad entry can't take the path itself or a parent with it.
+func (r *RotationManager) removeEntry(file *File) error {
+	if !file.Directory {
+		return r.provider.Delete(file.Path)
+	}
+
+	deleter, ok := r.provider.(providers.DirectoryDeleter)
+	if !ok {
+		return fmt.Errorf("%w %s: not supported by the provider", ErrDirectoryDelete, file.Path)
+	}
+	if !isBelow(r.path, file.Path, r.listOptions.RawPrefix) {
+		return fmt.Errorf("%w %s: not below %s", ErrDirectoryDelete, file.Path, r.path)
+	}
+	return deleter.DeleteDirectory(file.Path)
+}
+
+// EmptyDirectories returns the directories below the rotated path left empty once the files and their related
+// files are deleted, deepest first, for providers keeping empty directories around.
+func (r *RotationManager) EmptyDirectories(deleted []*File) ([]string, error) {
+	remover, ok := r.provider.(providers.EmptyDirectoryRemover)
+	if !ok {
+		return nil, fmt.Errorf("%w: empty directories not supported by the provider", ErrDirectoryDelete)
+	}
+
+	var paths []string
+	for _, file := range deleted {
+		paths = append(paths, file.Path)
+		for _, related := range file.Related {
+			paths = append(paths, related.Path)
+		}
+	}
+	return remover.EmptyDirectories(r.path, paths)
+}
+
+// RemoveEmptyDirectory deletes a directory below the rotated path, only if it is empty.
+func (r *RotationManager) RemoveEmptyDirectory(fullPath string) error {
+	remover, ok := r.provider.(providers.EmptyDirectoryRemover)
+	if !ok {
+		return fmt.Errorf("%w %s: not supported by the provider", ErrDirectoryDelete, fullPath)
+	}
+	if !isBelow(r.path, fullPath, false) {
+		return fmt.Errorf("%w %s: not below %s", ErrDirectoryDelete, fullPath, r.path)
+	}
+	return remover.DeleteEmptyDirectory(fullPath)
+}
+
+// isBelow reports whether the path is strictly below the root directory, or extends the root when it is
+// a raw cloud prefix.
+func isBelow(root, path string, rawPrefix bool) bool {
+	directory := !rawPrefix || !strings.Contains(root, "://")
+	root, path = withoutScheme(root), withoutScheme(path)
+	if directory {
+		root = strings.TrimSuffix(root, "/") + "/"
+	}
+	if root == "./" {
+		return path != "." && path != ".." && !strings.HasPrefix(path, "../") && !strings.HasPrefix(path, "/")
+	}
+	return len(path) > len(root) && strings.HasPrefix(path, root)
+}
+
+// RotateFiles retrieves the files and categorizes them based on the retention policy and the current time.
+// When too few files are left to rotate, it returns the error along with a summary listing the files left out,
+// so a pattern matching nothing or a filter excluding everything can still be reported. A listing that left
+// the uploads in progress out is still rotated, the error being returned in the summary's warnings.
 func (r *RotationManager) RotateFiles() (*Summary, error) {
 	fileList, err := r.ListFiles(r.path)
-	if err != nil {
+	var warnings []error
+	if errors.Is(err, providers.ErrUploadsNotListed) {
+		warnings = append(warnings, err)
+	} else if err != nil {
 		return nil, err
 	}
 
+	fileList, excluded := r.FilterFiles(fileList)
+	fileList, inProgress := r.SkipInProgress(fileList)
+	fileList, unmatched := r.ParseTimestamps(fileList)
+
 	if err := r.Validate(fileList); err != nil {
+		if errors.Is(err, ErrEmptyFileList) || errors.Is(err, ErrSingleFile) {
+			return &Summary{Warnings: warnings, Unmatched: unmatched, Excluded: excluded, InProgress: inProgress}, err
+		}
 		return nil, err
 	}
 
-	return RotateFilesOf(fileList, r.rotationScheme, carbon.Now()), nil
+	summary := r.policy.Apply(fileList, carbon.Now())
+	summary.Unmatched = unmatched
+	summary.Excluded = excluded
+	summary.InProgress = inProgress
+	summary.Warnings = warnings
+	return summary, nil
 }
 
 // RotateFilesOf categorizes the files based on the rotation scheme and the current time.
+// The default rolling mode keeps files within windows relative to the current time, while the calendar mode
+// keeps one file per calendar bucket. In both modes the scheme's selection strategy picks the file
+// representing each period. The exponential mode thins files out by age instead, and the Hanoi mode
+// keeps the files of a Tower of Hanoi rotation. Every timestamp is converted to the scheme's time zone first, so calendar checks don't depend on the host zone.
+//
+// The conversion is made on the files themselves: the summary lists the caller's files, not copies, so policies
+// wrapping the scheme can match them, and their timestamps are left in the scheme's zone and week start.
 func RotateFilesOf(files []*File, scheme *RotationScheme, current carbon.Carbon) *Summary {
-	sort.Sort(Files(files))
-
-	var hourly, daily, weekly, monthly, yearly, forDelete Files
-	var prevYearly, prevMonthly, prevWeekly, prevDaily, prevHourly *carbon.Carbon
-	var totalSizeHourly, totalSizeDaily, totalSizeWeekly, totalSizeMonthly, totalSizeYearly, totalSizeForDelete int64
-
+	current = scheme.In(current)
 	for _, file := range files {
-		addedToCategory := false
-
-		if file.IsHourlyOf(current, prevHourly) && len(hourly) < scheme.Hourly {
-			hourly = append(hourly, file)
-			prevHourly = &file.Timestamp
-			totalSizeHourly += file.Size
-			addedToCategory = true
-		}
-
-		if file.IsDailyOf(current, prevDaily) && len(daily) < scheme.Daily {
-			daily = append(daily, file)
-			prevDaily = &file.Timestamp
-			totalSizeDaily += file.Size
-			addedToCategory = true
-		}
-
-		if file.IsWeeklyOf(current, prevWeekly, scheme.Weekly) && len(weekly) < scheme.Weekly {
-			weekly = append(weekly, file)
-			prevWeekly = &file.Timestamp
-			totalSizeWeekly += file.Size
-			addedToCategory = true
-		}
+		file.Timestamp = scheme.In(file.Timestamp)
+	}
 
-		if file.IsMonthlyOf(current, prevMonthly) && len(monthly) < scheme.Monthly {
-			monthly = append(monthly, file)
-			prevMonthly = &file.Timestamp
-			totalSizeMonthly += file.Size
-			addedToCategory = true
-		}
+	files = sortedFiles(files)
 
-		if file.IsYearlyOf(current, prevYearly) {
-			if scheme.Yearly == -1 || len(yearly) < scheme.Yearly {
-				yearly = append(yearly, file)
-				prevYearly = &file.Timestamp
-				totalSizeYearly += file.Size
-				addedToCategory = true
+	summary := &Summary{Reasons: make(map[*File]string)}
+	for _, rule := range scheme.tierRules(files, current) {
+		kept := selectTier(files, rule.window, rule.bucket, rule.strategy, rule.limit)
+		for _, file := range kept {
+			reason := rule.name
+			if rule.reason != nil {
+				reason = rule.reason(file)
 			}
+			summary.addReason(file, reason)
 		}
-
-		if !addedToCategory {
-			forDelete = append(forDelete, file)
-			totalSizeForDelete += file.Size
-		}
-	}
-
-	return &Summary{
-		Hourly:             hourly,
-		Daily:              daily,
-		Weekly:             weekly,
-		Monthly:            monthly,
-		Yearly:             yearly,
-		ForDelete:          forDelete,
-		SizeTotalHourly:    totalSizeHourly,
-		SizeTotalDaily:     totalSizeDaily,
-		SizeTotalWeekly:    totalSizeWeekly,
-		SizeTotalMonthly:   totalSizeMonthly,
-		SizeTotalYearly:    totalSizeYearly,
-		SizeTotalForDelete: totalSizeForDelete,
+		summary.Tiers = append(summary.Tiers, &TierSummary{Name: rule.name, Files: kept})
 	}
+	summary.fill(files)
+	return summary
 }
@@ -17,9 +17,11 @@ limitations under the License.
 package rotate_test
 
 import (
+	"fmt"
 	"testing"
 
 	"github.com/golang-module/carbon"
+	"github.com/raniellyferreira/rotate-files/pkg/providers"
 	"github.com/raniellyferreira/rotate-files/pkg/rotate"
 	"github.com/stretchr/testify/assert"
 )
@@ -199,8 +201,14 @@ func TestDeleteMonthlyBackupsStartsMonth(t *testing.T) {
 	summaryBackups := rotate.RotateFilesOf(backups, &rotationScheme, today)
 
 	assert.Equal(t, rotationScheme.Monthly, len(summaryBackups.Monthly))
-	assert.Equal(t, 2, len(summaryBackups.Yearly))            // Atualizado para refletir o resultado real
-	assert.Equal(t, 16, summaryBackups.GetTotalCategorized()) // Atualizado para refletir o resultado real
+	assert.Equal(t, 2, len(summaryBackups.Yearly)) // Atualizado para refletir o resultado real
+
+	// Weeklies are picked on any day of the week, so the December and November backups, within the last
+	// ten weeks, are weeklies as well as monthlies. The total counts a file once per tier keeping it:
+	// 2 weeklies, 12 monthlies and 2 yearlies, plus backup_1b and backup_14 to delete.
+	assert.Equal(t, []string{"/backup_1", "/backup_2"}, paths(summaryBackups.Weekly))
+	assert.Equal(t, []string{"/backup_1b", "/backup_14"}, paths(summaryBackups.ForDelete))
+	assert.Equal(t, 2+12+2+2, summaryBackups.GetTotalCategorized())
 }
 
 func TestDeleteYearlyBackupsWithNoLimitTest(t *testing.T) {
@@ -227,3 +235,330 @@ func TestDeleteYearlyBackupsWithNoLimitTest(t *testing.T) {
 	assert.Equal(t, 0, len(summaryBackups.ForDelete))
 	assert.Equal(t, len(backups), summaryBackups.GetTotalCategorized())
 }
+
+func TestRotateFilesOfKeepsCallerOrder(t *testing.T) {
+	today := carbon.CreateFromDateTime(2024, 6, 15, 10, 0, 0, "UTC")
+	backups := []*rotate.File{
+		{Path: "/oldest", Timestamp: today.SubDays(3)},
+		{Path: "/newest", Timestamp: today.SubDays(1)},
+		{Path: "/middle", Timestamp: today.SubDays(2)},
+	}
+
+	summary := rotate.RotateFilesOf(backups, &rotate.RotationScheme{Daily: 7, Timezone: "UTC"}, today)
+
+	assert.Equal(t, []string{"/newest", "/middle", "/oldest"}, paths(summary.Daily))
+	assert.Equal(t, []string{"/oldest", "/newest", "/middle"}, paths(backups))
+}
+
+func TestRotateFilesOfTimezone(t *testing.T) {
+	current := carbon.CreateFromDateTime(2024, 6, 5, 12, 0, 0, "UTC")
+
+	newBackups := func() []*rotate.File {
+		return []*rotate.File{
+			// 2024-06-01 22:00 in São Paulo, 2024-06-02 in UTC
+			{Path: "/backup_late", Timestamp: carbon.CreateFromDateTime(2024, 6, 2, 1, 0, 0, "UTC")},
+			// 2024-06-01 10:00 in São Paulo and in UTC
+			{Path: "/backup_early", Timestamp: carbon.CreateFromDateTime(2024, 6, 1, 13, 0, 0, "UTC")},
+		}
+	}
+
+	saoPaulo := rotate.RotateFilesOf(newBackups(), &rotate.RotationScheme{Daily: 7, Timezone: "America/Sao_Paulo"}, current)
+	assert.Equal(t, 1, len(saoPaulo.Daily))
+	assert.Equal(t, "/backup_late", saoPaulo.Daily[0].Path)
+	assert.Equal(t, "America/Sao_Paulo", saoPaulo.Daily[0].Timestamp.Location())
+
+	utc := rotate.RotateFilesOf(newBackups(), &rotate.RotationScheme{Daily: 7, Timezone: "UTC"}, current)
+	assert.Equal(t, 2, len(utc.Daily))
+}
+
+func TestRotateFilesOfDSTStart(t *testing.T) {
+	// Clocks in São Paulo jumped from 00:00 to 01:00 on 2018-11-04, so that day had 23 hours.
+	tz := "America/Sao_Paulo"
+	current := carbon.CreateFromDateTime(2018, 11, 10, 12, 0, 0, tz)
+
+	backups := []*rotate.File{
+		{Path: "/backup_4_late", Timestamp: carbon.CreateFromDateTime(2018, 11, 4, 23, 30, 0, tz)},
+		{Path: "/backup_4_early", Timestamp: carbon.CreateFromDateTime(2018, 11, 4, 1, 30, 0, tz)},
+		{Path: "/backup_3_late", Timestamp: carbon.CreateFromDateTime(2018, 11, 3, 23, 30, 0, tz)},
+	}
+
+	// The host zone must not matter once the scheme carries a location.
+	summary := rotate.RotateFilesOf(backups, &rotate.RotationScheme{Daily: 7, Timezone: tz}, current.SetTimezone("Asia/Tokyo"))
+
+	assert.Equal(t, 2, len(summary.Daily))
+	assert.Equal(t, "/backup_4_late", summary.Daily[0].Path)
+	assert.Equal(t, "/backup_3_late", summary.Daily[1].Path)
+	assert.Equal(t, 1, len(summary.ForDelete))
+	assert.Equal(t, "/backup_4_early", summary.ForDelete[0].Path)
+}
+
+func TestRotateFilesOfDSTEnd(t *testing.T) {
+	// Clocks in São Paulo went back from 2019-02-17 00:00 to 2019-02-16 23:00, repeating the 23h hour.
+	tz := "America/Sao_Paulo"
+	current := carbon.CreateFromDateTime(2019, 2, 17, 5, 0, 0, "UTC")
+
+	backups := []*rotate.File{
+		// 23:30 -03:00, after the clocks went back
+		{Path: "/backup_second_2330", Timestamp: carbon.CreateFromDateTime(2019, 2, 17, 2, 30, 0, "UTC")},
+		// 23:30 -02:00, before the clocks went back
+		{Path: "/backup_first_2330", Timestamp: carbon.CreateFromDateTime(2019, 2, 17, 1, 30, 0, "UTC")},
+		// 22:30 -02:00
+		{Path: "/backup_2230", Timestamp: carbon.CreateFromDateTime(2019, 2, 17, 0, 30, 0, "UTC")},
+	}
+
+	summary := rotate.RotateFilesOf(backups, &rotate.RotationScheme{Hourly: 24, Timezone: tz}, current)
+
+	assert.Equal(t, 2, len(summary.Hourly))
+	assert.Equal(t, "/backup_second_2330", summary.Hourly[0].Path)
+	assert.Equal(t, "/backup_2230", summary.Hourly[1].Path)
+	assert.Equal(t, 1, len(summary.ForDelete))
+	assert.Equal(t, "/backup_first_2330", summary.ForDelete[0].Path)
+}
+
+func TestFileIsSameDayAcrossZones(t *testing.T) {
+	file := rotate.File{Timestamp: carbon.CreateFromDateTime(2024, 6, 1, 22, 0, 0, "America/Sao_Paulo")}
+
+	// 2024-06-02 01:00 UTC is still 2024-06-01 in São Paulo
+	assert.True(t, file.IsSameDay(carbonPtr(carbon.CreateFromDateTime(2024, 6, 2, 1, 0, 0, "UTC"))))
+	assert.False(t, file.IsSameDay(carbonPtr(carbon.CreateFromDateTime(2024, 6, 2, 4, 0, 0, "UTC"))))
+}
+
+func TestRotateFilesOfWindowsFollowCounts(t *testing.T) {
+	today := carbon.CreateFromDateTime(2024, 6, 15, 10, 30, 0, "UTC")
+
+	tests := []struct {
+		name     string
+		scheme   rotate.RotationScheme
+		backups  int
+		step     func(carbon.Carbon, int) carbon.Carbon
+		category func(*rotate.Summary) []*rotate.File
+		expected int
+	}{
+		{
+			name:     "Daily 30 keeps 30 distinct days",
+			scheme:   rotate.RotationScheme{Daily: 30, Timezone: "UTC"},
+			backups:  45,
+			step:     func(c carbon.Carbon, i int) carbon.Carbon { return c.SubDays(i) },
+			category: func(s *rotate.Summary) []*rotate.File { return s.Daily },
+			expected: 30,
+		},
+		{
+			name:     "Hourly 72 keeps 72 distinct hours",
+			scheme:   rotate.RotationScheme{Hourly: 72, Timezone: "UTC"},
+			backups:  100,
+			step:     func(c carbon.Carbon, i int) carbon.Carbon { return c.SubHours(i) },
+			category: func(s *rotate.Summary) []*rotate.File { return s.Hourly },
+			expected: 72,
+		},
+		{
+			name:     "Monthly 24 keeps 24 distinct months",
+			scheme:   rotate.RotationScheme{Monthly: 24, Timezone: "UTC"},
+			backups:  36,
+			step:     func(c carbon.Carbon, i int) carbon.Carbon { return c.SubMonths(i) },
+			category: func(s *rotate.Summary) []*rotate.File { return s.Monthly },
+			expected: 24,
+		},
+		{
+			name:     "Daily 3 keeps 3 days only",
+			scheme:   rotate.RotationScheme{Daily: 3, Timezone: "UTC"},
+			backups:  10,
+			step:     func(c carbon.Carbon, i int) carbon.Carbon { return c.SubDays(i) },
+			category: func(s *rotate.Summary) []*rotate.File { return s.Daily },
+			expected: 3,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var backups []*rotate.File
+			for i := 1; i <= test.backups; i++ {
+				backups = append(backups, &rotate.File{
+					Path:      "/backup_" + test.step(today, i).ToDateTimeString(),
+					Timestamp: test.step(today, i),
+				})
+			}
+
+			// One backup per period, so every kept file is a distinct period.
+			summary := rotate.RotateFilesOf(backups, &test.scheme, today)
+			assert.Equal(t, test.expected, len(test.category(summary)))
+		})
+	}
+}
+
+func TestRotateFilesOfWeeklyWithoutSunday(t *testing.T) {
+	// 2024-06-15 is a Saturday
+	today := carbon.CreateFromDateTime(2024, 6, 15, 10, 0, 0, "UTC")
+
+	var backups []*rotate.File
+	for i := 1; i <= 6; i++ {
+		// Weekly job running on Saturdays only
+		backups = append(backups, &rotate.File{Path: fmt.Sprintf("/saturday_%d", i), Timestamp: today.SubWeeks(i)})
+	}
+
+	summary := rotate.RotateFilesOf(backups, &rotate.RotationScheme{Weekly: 4, Timezone: "UTC"}, today)
+
+	assert.Equal(t, 4, len(summary.Weekly))
+	assert.Equal(t, "/saturday_1", summary.Weekly[0].Path)
+	assert.Equal(t, "/saturday_4", summary.Weekly[3].Path)
+}
+
+func TestRotateFilesOfWeeklySkipsCurrentWeek(t *testing.T) {
+	// 2024-06-19 is a Wednesday, in the week starting on Sunday 2024-06-16
+	today := carbon.CreateFromDateTime(2024, 6, 19, 10, 0, 0, "UTC")
+
+	backups := []*rotate.File{
+		{Path: "/tuesday", Timestamp: carbon.CreateFromDateTime(2024, 6, 18, 3, 0, 0, "UTC")},
+		{Path: "/last_week", Timestamp: carbon.CreateFromDateTime(2024, 6, 13, 3, 0, 0, "UTC")},
+	}
+
+	// Like the days and months in progress, the week in progress isn't a weekly backup yet
+	summary := rotate.RotateFilesOf(backups, &rotate.RotationScheme{Weekly: 4, Timezone: "UTC"}, today)
+	assert.Equal(t, 1, len(summary.Weekly))
+	assert.Equal(t, "/last_week", summary.Weekly[0].Path)
+	assert.Equal(t, "/tuesday", summary.ForDelete[0].Path)
+}
+
+func TestRotateFilesOfWeeklyMidnightWeekStart(t *testing.T) {
+	// 2024-06-19 is a Wednesday, in the week starting on Sunday 2024-06-16
+	today := carbon.CreateFromDateTime(2024, 6, 19, 10, 0, 0, "UTC")
+
+	backups := []*rotate.File{
+		{Path: "/this_sunday", Timestamp: carbon.CreateFromDateTime(2024, 6, 16, 0, 0, 0, "UTC").StartOfDay()},
+		{Path: "/last_saturday", Timestamp: carbon.CreateFromDateTime(2024, 6, 15, 0, 0, 0, "UTC").StartOfDay()},
+		{Path: "/last_sunday", Timestamp: carbon.CreateFromDateTime(2024, 6, 9, 0, 0, 0, "UTC").StartOfDay()},
+	}
+
+	// A backup taken exactly at midnight on the week start belongs to the week it starts
+	assert.True(t, backups[0].IsSameWeek(&today))
+	assert.True(t, backups[2].IsSameWeek(&backups[1].Timestamp))
+
+	summary := rotate.RotateFilesOf(backups, &rotate.RotationScheme{Weekly: 4, Timezone: "UTC"}, today)
+	assert.Equal(t, []string{"/last_saturday"}, paths(summary.Weekly))
+	assert.Equal(t, []string{"/this_sunday", "/last_sunday"}, paths(summary.ForDelete))
+}
+
+func TestRotateFilesOfWeekStartsAt(t *testing.T) {
+	// 2024-06-19 is a Wednesday
+	today := carbon.CreateFromDateTime(2024, 6, 19, 10, 0, 0, "UTC")
+
+	newBackups := func() []*rotate.File {
+		return []*rotate.File{
+			{Path: "/monday", Timestamp: carbon.CreateFromDateTime(2024, 6, 10, 3, 0, 0, "UTC")},
+			{Path: "/sunday", Timestamp: carbon.CreateFromDateTime(2024, 6, 9, 3, 0, 0, "UTC")},
+			{Path: "/saturday", Timestamp: carbon.CreateFromDateTime(2024, 6, 8, 3, 0, 0, "UTC")},
+		}
+	}
+
+	// Weeks starting on Sunday: [sunday, monday] and [saturday]
+	sunday := rotate.RotateFilesOf(newBackups(), &rotate.RotationScheme{Weekly: 4, Timezone: "UTC", WeekStartsAt: carbon.Sunday}, today)
+	assert.Equal(t, 2, len(sunday.Weekly))
+	assert.Equal(t, "/monday", sunday.Weekly[0].Path)
+	assert.Equal(t, "/saturday", sunday.Weekly[1].Path)
+
+	// ISO weeks starting on Monday: [monday] and [saturday, sunday]
+	monday := rotate.RotateFilesOf(newBackups(), &rotate.RotationScheme{Weekly: 4, Timezone: "UTC", WeekStartsAt: carbon.Monday}, today)
+	assert.Equal(t, 2, len(monday.Weekly))
+	assert.Equal(t, "/monday", monday.Weekly[0].Path)
+	assert.Equal(t, "/sunday", monday.Weekly[1].Path)
+}
+
+func TestParseWeekStart(t *testing.T) {
+	day, err := rotate.ParseWeekStart("monday")
+	assert.NoError(t, err)
+	assert.Equal(t, carbon.Monday, day)
+
+	_, err = rotate.ParseWeekStart("someday")
+	assert.ErrorIs(t, err, rotate.ErrInvalidWeekStart)
+}
+
+func TestRotateFilesOfMinutely(t *testing.T) {
+	today := carbon.CreateFromDateTime(2024, 6, 15, 10, 0, 0, "UTC")
+
+	// A snapshot every 5 minutes over the last two hours
+	var backups []*rotate.File
+	for i := 0; i <= 24; i++ {
+		timestamp := today.SubMinutes(i * 5)
+		backups = append(backups, &rotate.File{Path: timestamp.ToDateTimeString(), Timestamp: timestamp})
+	}
+
+	scheme := rotate.RotationScheme{Minutely: 6, MinutelyInterval: 15, Timezone: "UTC"}
+	summary := rotate.RotateFilesOf(backups, &scheme, today)
+
+	assert.Equal(t, []string{
+		"2024-06-15 10:00:00",
+		"2024-06-15 09:55:00",
+		"2024-06-15 09:40:00",
+		"2024-06-15 09:25:00",
+		"2024-06-15 09:10:00",
+		"2024-06-15 08:55:00",
+	}, paths(summary.Minutely))
+	assert.Equal(t, len(backups)-6, len(summary.ForDelete))
+}
+
+func TestRotateFilesOfQuarterly(t *testing.T) {
+	today := carbon.CreateFromDateTime(2024, 6, 15, 10, 0, 0, "UTC")
+
+	// Monthly backups over the last eight years
+	var backups []*rotate.File
+	for i := 0; i < 96; i++ {
+		timestamp := carbon.CreateFromDateTime(2024, 6, 1, 0, 0, 0, "UTC").SubMonths(i)
+		backups = append(backups, &rotate.File{Path: timestamp.ToDateString(), Timestamp: timestamp, Size: 1})
+	}
+
+	// Quarter-end backups for seven years, the quarter in progress excluded
+	scheme := rotate.RotationScheme{Quarterly: 28, Timezone: "UTC"}
+	summary := rotate.RotateFilesOf(backups, &scheme, today)
+
+	assert.Equal(t, 28, len(summary.Quarterly))
+	assert.Equal(t, "2024-03-01", summary.Quarterly[0].Path)
+	assert.Equal(t, "2017-06-01", summary.Quarterly[27].Path)
+	assert.Equal(t, int64(28), summary.SizeTotalQuarterly)
+	assert.Equal(t, 96-28, len(summary.ForDelete))
+}
+
+func TestFileIsSameFiscalYear(t *testing.T) {
+	march := rotate.File{Timestamp: carbon.CreateFromDateTime(2024, 3, 31, 12, 0, 0, "UTC")}
+	january := carbon.CreateFromDateTime(2024, 1, 15, 12, 0, 0, "UTC")
+	april := carbon.CreateFromDateTime(2024, 4, 1, 12, 0, 0, "UTC")
+
+	assert.True(t, march.IsSameFiscalYear(&april, 1))
+	assert.False(t, march.IsSameFiscalYear(&april, 4))
+	assert.True(t, march.IsSameFiscalYear(&january, 4))
+	assert.True(t, march.IsSameQuarter(&january, 4))
+	assert.False(t, march.IsSameQuarter(&april, 4))
+}
+
+func TestSizeReclaimed(t *testing.T) {
+	today := carbon.CreateFromDateTime(2024, 6, 15, 10, 0, 0, "UTC")
+	inode := func(number, links uint64) providers.Inode {
+		return providers.Inode{Device: 1, Number: number, Links: links, Allocated: 4096}
+	}
+
+	// Snapshots hard link the unchanged base file, each adding its own changes
+	backups := []*rotate.File{
+		{Path: "daily.0", Size: 2000, Timestamp: today, Directory: true, Inodes: []providers.Inode{inode(1, 3), inode(2, 1)}},
+		{Path: "daily.1", Size: 2000, Timestamp: today.SubDay(), Directory: true, Inodes: []providers.Inode{inode(1, 3), inode(3, 1)}},
+		{Path: "daily.2", Size: 2000, Timestamp: today.SubDays(2), Directory: true, Inodes: []providers.Inode{inode(1, 3), inode(4, 2)}},
+		{Path: "remote", Size: 500, Timestamp: today.SubDays(3)},
+	}
+
+	summary := rotate.RotateFilesOf(backups, &rotate.RotationScheme{KeepLast: 1, Timezone: "UTC"}, today)
+	assert.Equal(t, int64(4500), summary.SizeTotalForDelete)
+	// The base file is still linked from the kept snapshot, and the inode 4 from outside the listing
+	assert.Equal(t, int64(4096+500), summary.SizeReclaimed)
+	assert.Equal(t, 2, summary.SharedInodes)
+
+	// Once every snapshot goes, so does the base file
+	summary = rotate.RotateFilesOf(backups, &rotate.RotationScheme{Timezone: "UTC"}, today)
+	assert.Equal(t, int64(3*4096+500), summary.SizeReclaimed)
+	assert.Equal(t, 1, summary.SharedInodes)
+
+	// Files not hard linked elsewhere free their allocated size, which differs from the apparent one
+	single := []*rotate.File{
+		{Path: "new", Size: 100, Timestamp: today, Inodes: []providers.Inode{inode(5, 1)}},
+		{Path: "old", Size: 100, Timestamp: today.SubDay(), Inodes: []providers.Inode{inode(6, 1)}},
+	}
+	summary = rotate.RotateFilesOf(single, &rotate.RotationScheme{KeepLast: 1, Timezone: "UTC"}, today)
+	assert.Equal(t, int64(4096), summary.SizeReclaimed)
+	assert.Equal(t, 0, summary.SharedInodes)
+}
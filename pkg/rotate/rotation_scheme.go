@@ -16,12 +16,148 @@ limitations under the License.
 
 package rotate
 
-// RotationScheme represents the configuration for rotating backups, including hourly, daily, weekly, monthly, and yearly limits.
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/golang-module/carbon"
+)
+
+// Rotation modes.
+const (
+	// ModeRolling keeps files within windows relative to the current time. It is the default.
+	ModeRolling = "rolling"
+	// ModeCalendar keeps one file per calendar minute interval, hour, day, week, month, quarter and year,
+	// and per period of the user-defined tiers: the oldest of each period, unless a selection strategy says otherwise.
+	ModeCalendar = "calendar"
+	// ModeExponential keeps one file per exponentially growing age interval instead of the tiers.
+	ModeExponential = "exponential"
+	// ModeHanoi keeps the files of a Tower of Hanoi rotation instead of the tiers.
+	ModeHanoi = "hanoi"
+)
+
+// weekdays lists the carbon week day names accepted as the start of the week.
+var weekdays = []string{
+	carbon.Sunday, carbon.Monday, carbon.Tuesday, carbon.Wednesday,
+	carbon.Thursday, carbon.Friday, carbon.Saturday,
+}
+
+// RotationScheme represents the configuration for rotating backups, including minutely, hourly, daily, weekly, monthly,
+// quarterly and yearly limits. Minutely backups are kept per MinutelyInterval minutes (one when zero), and quarters and
+// years start at the FiscalYearStart month (January when zero).
+// Timezone is the IANA name of the zone the backup calendar is evaluated in; empty means the host's local zone.
+// WeekStartsAt is the carbon day name calendar weeks start on (e.g. carbon.Monday for ISO weeks); empty means Sunday.
+// Mode selects how files are categorized, ModeRolling when empty.
+// Selection chooses which backup represents each period of a tier. A negative tier limit keeps every period.
+// Tiers adds user-defined tiers, evaluated after the built-in ones.
+// Whatever the tiers, KeepLast keeps the most recent files, KeepWithin every file not older than the duration,
+// and KeepWithinDaily one file per day within the duration, picked as in the daily tier.
+// In ModeExponential the age intervals start at ExponentialBase and double up to ExponentialMaxAge, unlimited when zero.
+// In ModeHanoi every HanoiInterval is a slot of a rotation with HanoiLevels levels.
 type RotationScheme struct {
-	Hourly  int
-	Daily   int
-	Weekly  int
-	Monthly int
-	Yearly  int
-	DryRun  bool
+	Minutely         int
+	MinutelyInterval int
+	Hourly           int
+	Daily            int
+	Weekly           int
+	Monthly          int
+	Quarterly        int
+	Yearly           int
+	FiscalYearStart  int
+	DryRun           bool
+	Timezone         string
+	WeekStartsAt     string
+	Mode             string
+	Selection        TierSelection
+	Tiers            []Tier
+	KeepLast         int
+	KeepWithin       time.Duration
+	KeepWithinDaily  time.Duration
+
+	ExponentialBase   time.Duration
+	ExponentialMaxAge time.Duration
+
+	HanoiLevels   int
+	HanoiInterval time.Duration
+}
+
+// ParseWeekStart returns the carbon day name for a case-insensitive week day name such as "monday".
+func ParseWeekStart(day string) (string, error) {
+	for _, weekday := range weekdays {
+		if strings.EqualFold(weekday, strings.TrimSpace(day)) {
+			return weekday, nil
+		}
+	}
+	return "", fmt.Errorf("%w: %s", ErrInvalidWeekStart, day)
+}
+
+// Apply categorizes the files with RotateFilesOf, making the scheme a Policy. Like RotateFilesOf, it converts
+// the timestamps of the files to the scheme's time zone.
+func (s *RotationScheme) Apply(files []*File, current carbon.Carbon) *Summary {
+	return RotateFilesOf(files, s, current)
+}
+
+// Validate checks the scheme's time zone, mode, selection, fiscal year start, tiers and week start.
+func (s *RotationScheme) Validate() error {
+	if s == nil {
+		return errNilScheme
+	}
+
+	if _, err := s.Location(); err != nil {
+		return fmt.Errorf("%w: %s", ErrInvalidTimezone, s.Timezone)
+	}
+
+	switch s.Mode {
+	case "", ModeRolling, ModeCalendar:
+	case ModeExponential:
+		if s.ExponentialBase <= 0 {
+			return fmt.Errorf("%w: %s needs a positive base interval", ErrInvalidMode, ModeExponential)
+		}
+	case ModeHanoi:
+		if s.HanoiLevels <= 0 || s.HanoiInterval <= 0 {
+			return fmt.Errorf("%w: %s needs positive levels and interval", ErrInvalidMode, ModeHanoi)
+		}
+	default:
+		return fmt.Errorf("%w: %s", ErrInvalidMode, s.Mode)
+	}
+
+	if err := s.Selection.Validate(); err != nil {
+		return err
+	}
+
+	if s.FiscalYearStart < 0 || s.FiscalYearStart > 12 {
+		return fmt.Errorf("%w: %d", ErrInvalidFiscalYearStart, s.FiscalYearStart)
+	}
+
+	if err := validateTiers(s.Tiers); err != nil {
+		return err
+	}
+
+	if s.WeekStartsAt != "" {
+		if _, err := ParseWeekStart(s.WeekStartsAt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Location returns the time zone the rotation calendar is evaluated in.
+func (s *RotationScheme) Location() (*time.Location, error) {
+	if s.Timezone == "" {
+		return time.Local, nil
+	}
+	return time.LoadLocation(s.Timezone)
+}
+
+// In converts a timestamp to the scheme's time zone and week start, keeping the same instant.
+func (s *RotationScheme) In(c carbon.Carbon) carbon.Carbon {
+	if s.WeekStartsAt != "" {
+		c = c.SetWeekStartsAt(s.WeekStartsAt)
+	}
+	loc, err := s.Location()
+	if err != nil {
+		return c
+	}
+	return c.SetLocation(loc)
 }
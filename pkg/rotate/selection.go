@@ -0,0 +1,167 @@
+/*
+Copyright The Rotate Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rotate
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/golang-module/carbon"
+)
+
+// Selection strategies choosing which backup represents a period. SelectDefault, parsed as the empty
+// strategy, selects the newest file, or the oldest in calendar mode.
+const (
+	SelectDefault = "default"
+	SelectNewest  = "newest"
+	SelectOldest  = "oldest"
+	SelectLargest = "largest"
+)
+
+// TierSelection holds the selection strategy of each tier. Empty strategies select the newest file,
+// or the oldest in calendar mode.
+type TierSelection struct {
+	Minutely  string
+	Hourly    string
+	Daily     string
+	Weekly    string
+	Monthly   string
+	Quarterly string
+	Yearly    string
+}
+
+// ParseTierSelection parses a strategy applied to every tier, such as "largest", or per-tier
+// strategies such as "monthly=oldest,daily=largest". Tiers left out, or set to "default", keep the empty strategy.
+func ParseTierSelection(value string) (TierSelection, error) {
+	var selection TierSelection
+
+	for _, token := range strings.Split(value, ",") {
+		token = strings.TrimSpace(token)
+		tier, strategy, found := strings.Cut(token, "=")
+		if !found {
+			strategy, tier = tier, ""
+		}
+		if strategy == SelectDefault {
+			strategy = ""
+		}
+		if err := validateSelection(strategy); err != nil {
+			return TierSelection{}, err
+		}
+
+		switch strings.ToLower(tier) {
+		case "":
+			selection = TierSelection{strategy, strategy, strategy, strategy, strategy, strategy, strategy}
+		case "minutely":
+			selection.Minutely = strategy
+		case "hourly":
+			selection.Hourly = strategy
+		case "daily":
+			selection.Daily = strategy
+		case "weekly":
+			selection.Weekly = strategy
+		case "monthly":
+			selection.Monthly = strategy
+		case "quarterly":
+			selection.Quarterly = strategy
+		case "yearly":
+			selection.Yearly = strategy
+		default:
+			return TierSelection{}, fmt.Errorf("%w: unknown tier %q", ErrInvalidSelection, tier)
+		}
+	}
+	return selection, nil
+}
+
+// Validate checks that every strategy is known.
+func (t TierSelection) Validate() error {
+	for _, strategy := range []string{t.Minutely, t.Hourly, t.Daily, t.Weekly, t.Monthly, t.Quarterly, t.Yearly} {
+		if err := validateSelection(strategy); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateSelection checks a single strategy, accepting empty as the default.
+func validateSelection(strategy string) error {
+	switch strategy {
+	case "", SelectNewest, SelectOldest, SelectLargest:
+		return nil
+	default:
+		return fmt.Errorf("%w: %q", ErrInvalidSelection, strategy)
+	}
+}
+
+// selectTier groups the files into buckets and returns one representative per bucket, newest buckets
+// first, up to the limit. Files outside the window are ignored; a nil window accepts every file.
+// A negative limit keeps every bucket, and a nil bucket puts every file in its own bucket.
+// The files must be sorted newest first.
+func selectTier(files Files, inWindow func(*File) bool, bucket func(carbon.Carbon) string, strategy string, limit int) []*File {
+	var kept []*File
+	var current []*File
+	var last string
+
+	flush := func() bool {
+		if len(current) == 0 {
+			return true
+		}
+		if limit >= 0 && len(kept) >= limit {
+			return false
+		}
+		kept = append(kept, pick(current, strategy))
+		current = nil
+		return true
+	}
+
+	for _, file := range files {
+		if inWindow != nil && !inWindow(file) {
+			continue
+		}
+		var key string
+		if bucket != nil {
+			key = bucket(file.Timestamp)
+		}
+		if len(current) > 0 && (bucket == nil || key != last) {
+			if !flush() {
+				return kept
+			}
+		}
+		current = append(current, file)
+		last = key
+	}
+	flush()
+	return kept
+}
+
+// pick returns the representative of a bucket, breaking ties by path.
+// The bucket must be sorted newest first, and by path for equal timestamps.
+func pick(bucket []*File, strategy string) *File {
+	best := bucket[0]
+	for _, file := range bucket[1:] {
+		switch strategy {
+		case SelectOldest:
+			if file.Timestamp.Lt(best.Timestamp) || (file.Timestamp.Eq(best.Timestamp) && file.Path < best.Path) {
+				best = file
+			}
+		case SelectLargest:
+			if file.Size > best.Size || (file.Size == best.Size && file.Path < best.Path) {
+				best = file
+			}
+		}
+	}
+	return best
+}
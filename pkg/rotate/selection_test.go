@@ -0,0 +1,102 @@
+/*
+Copyright The Rotate Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rotate_test
+
+import (
+	"testing"
+
+	"github.com/golang-module/carbon"
+	"github.com/raniellyferreira/rotate-files/pkg/rotate"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseTierSelection(t *testing.T) {
+	selection, err := rotate.ParseTierSelection("largest")
+	assert.NoError(t, err)
+	assert.Equal(t, rotate.TierSelection{
+		Minutely: "largest", Hourly: "largest", Daily: "largest", Weekly: "largest",
+		Monthly: "largest", Quarterly: "largest", Yearly: "largest",
+	}, selection)
+
+	selection, err = rotate.ParseTierSelection("monthly=oldest, daily=largest")
+	assert.NoError(t, err)
+	assert.Equal(t, rotate.TierSelection{Daily: "largest", Monthly: "oldest"}, selection)
+
+	selection, err = rotate.ParseTierSelection("default, daily=oldest")
+	assert.NoError(t, err)
+	assert.Equal(t, rotate.TierSelection{Daily: "oldest"}, selection)
+
+	_, err = rotate.ParseTierSelection("monthly=first")
+	assert.ErrorIs(t, err, rotate.ErrInvalidSelection)
+
+	_, err = rotate.ParseTierSelection("fortnightly=oldest")
+	assert.ErrorIs(t, err, rotate.ErrInvalidSelection)
+}
+
+func TestRotateFilesOfSelection(t *testing.T) {
+	today := carbon.CreateFromDateTime(2024, 6, 15, 10, 0, 0, "UTC")
+
+	newBackups := func() []*rotate.File {
+		return []*rotate.File{
+			{Path: "/june_13_late", Size: 100, Timestamp: carbon.CreateFromDateTime(2024, 6, 13, 22, 0, 0, "UTC")},
+			{Path: "/june_13_b", Size: 300, Timestamp: carbon.CreateFromDateTime(2024, 6, 13, 12, 0, 0, "UTC")},
+			{Path: "/june_13_a", Size: 300, Timestamp: carbon.CreateFromDateTime(2024, 6, 13, 12, 0, 0, "UTC")},
+			{Path: "/june_13_early", Size: 200, Timestamp: carbon.CreateFromDateTime(2024, 6, 13, 1, 0, 0, "UTC")},
+			{Path: "/april_30", Size: 100, Timestamp: carbon.CreateFromDateTime(2024, 4, 30, 1, 0, 0, "UTC")},
+			{Path: "/april_01", Size: 100, Timestamp: carbon.CreateFromDateTime(2024, 4, 1, 1, 0, 0, "UTC")},
+		}
+	}
+
+	tests := []struct {
+		name            string
+		mode            string
+		selection       rotate.TierSelection
+		expectedDaily   []string
+		expectedMonthly []string
+	}{
+		{
+			name:            "Newest by default",
+			mode:            rotate.ModeRolling,
+			expectedDaily:   []string{"/june_13_late"},
+			expectedMonthly: []string{"/april_30"},
+		},
+		{
+			name:            "Largest daily, ties broken by path",
+			mode:            rotate.ModeRolling,
+			selection:       rotate.TierSelection{Daily: rotate.SelectLargest, Monthly: rotate.SelectOldest},
+			expectedDaily:   []string{"/june_13_a"},
+			expectedMonthly: []string{"/april_01"},
+		},
+		{
+			name:            "Oldest daily in calendar mode",
+			mode:            rotate.ModeCalendar,
+			selection:       rotate.TierSelection{Daily: rotate.SelectOldest, Monthly: rotate.SelectOldest},
+			expectedDaily:   []string{"/june_13_early", "/april_30", "/april_01"},
+			expectedMonthly: []string{"/june_13_early", "/april_01"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			scheme := rotate.RotationScheme{Daily: 7, Monthly: 12, Timezone: "UTC", Mode: test.mode, Selection: test.selection}
+			summary := rotate.RotateFilesOf(newBackups(), &scheme, today)
+
+			assert.Equal(t, test.expectedDaily, paths(summary.Daily))
+			assert.Equal(t, test.expectedMonthly, paths(summary.Monthly))
+		})
+	}
+}
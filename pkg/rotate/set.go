@@ -0,0 +1,173 @@
+/*
+Copyright The Rotate Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rotate
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/golang-module/carbon"
+)
+
+// LinkByStemName is the ParseLinkBy value linking files sharing their stem.
+const LinkByStemName = "stem"
+
+// setStem returns the path without the extensions of its name, the dot-separated parts starting with a letter,
+// so "/backups/x-2024.tar.zst" and "/backups/x-2024.manifest.json" share the stem "/backups/x-2024", while
+// the dates of "backup.2024-06-14.tar.gz" and "full.2021.12.31T06.bak" stay in theirs. A stem without
+// a digit can't tell the backups apart, so the path is its own stem then.
+func setStem(path string) string {
+	name := baseName(path)
+	stem := name
+	for {
+		i := strings.LastIndex(stem, ".")
+		if i <= 0 || !isExtension(stem[i+1:]) {
+			break
+		}
+		stem = stem[:i]
+	}
+	if !strings.ContainsAny(stem, "0123456789") {
+		return path
+	}
+	return path[:len(path)-len(name)+len(stem)]
+}
+
+// isExtension reports whether a dot-separated part of a name is an extension, such as "tar" or "sha256",
+// rather than part of a timestamp.
+func isExtension(part string) bool {
+	r, _ := utf8.DecodeRuneInString(part)
+	return unicode.IsLetter(r)
+}
+
+// LinkByStem links the files sharing their stem, their name without its extensions, into sets. The largest file of a set, usually the archive,
+// represents it and holds the others in Related. It returns the representatives.
+func LinkByStem(files []*File) []*File {
+	sets := make(map[string][]*File)
+	var stems []string
+	for _, file := range files {
+		file.Related = nil
+		stem := setStem(file.Path)
+		if _, ok := sets[stem]; !ok {
+			stems = append(stems, stem)
+		}
+		sets[stem] = append(sets[stem], file)
+	}
+
+	primaries := make([]*File, 0, len(stems))
+	for _, stem := range stems {
+		set := sets[stem]
+		sort.SliceStable(set, func(i, j int) bool {
+			if set[i].Size != set[j].Size {
+				return set[i].Size > set[j].Size
+			}
+			return len(set[i].Path) < len(set[j].Path)
+		})
+		set[0].Related = set[1:]
+		primaries = append(primaries, set[0])
+	}
+	return primaries
+}
+
+// LinkBySuffixes returns a link function attaching the files ending with one of the suffixes, such as
+// ".sha256" or ".manifest.json", to the file they belong to: the file with the same path without the suffix,
+// or else the first one sharing its stem, as in LinkByStem, so "x-2024.tar.zst.sha256" and "x-2024.manifest.json" both belong to "x-2024.tar.zst".
+// Files without a suffix represent their set. A sidecar without its file represents itself, so it is
+// rotated on its own instead of kept forever.
+func LinkBySuffixes(suffixes ...string) func([]*File) []*File {
+	return func(files []*File) []*File {
+		byPath := make(map[string]*File)
+		byStem := make(map[string]*File)
+		var primaries, sidecars []*File
+		for _, file := range files {
+			file.Related = nil
+			if sidecarSuffix(file.Path, suffixes) != "" {
+				sidecars = append(sidecars, file)
+				continue
+			}
+			primaries = append(primaries, file)
+			byPath[file.Path] = file
+			if _, ok := byStem[setStem(file.Path)]; !ok {
+				byStem[setStem(file.Path)] = file
+			}
+		}
+
+		for _, sidecar := range sidecars {
+			path := strings.TrimSuffix(sidecar.Path, sidecarSuffix(sidecar.Path, suffixes))
+			primary, ok := byPath[path]
+			if !ok {
+				primary, ok = byStem[setStem(path)]
+			}
+			if !ok {
+				primaries = append(primaries, sidecar)
+				continue
+			}
+			primary.Related = append(primary.Related, sidecar)
+		}
+		return primaries
+	}
+}
+
+// sidecarSuffix returns the longest of the suffixes ending the base name of the path, if any.
+func sidecarSuffix(path string, suffixes []string) string {
+	name := baseName(path)
+	longest := ""
+	for _, suffix := range suffixes {
+		if len(suffix) > len(longest) && len(suffix) < len(name) && strings.HasSuffix(name, suffix) {
+			longest = suffix
+		}
+	}
+	return longest
+}
+
+// ParseLinkBy returns the link function for "stem" or for a comma-separated list of sidecar suffixes,
+// such as ".sha256,.manifest.json".
+func ParseLinkBy(value string) (func([]*File) []*File, error) {
+	if value == LinkByStemName {
+		return LinkByStem, nil
+	}
+
+	var suffixes []string
+	for _, suffix := range strings.Split(value, ",") {
+		suffix = strings.TrimSpace(suffix)
+		if suffix == "" || strings.ContainsAny(suffix, `/\`) {
+			return nil, fmt.Errorf("invalid sidecar suffix %q", suffix)
+		}
+		suffixes = append(suffixes, suffix)
+	}
+	return LinkBySuffixes(suffixes...), nil
+}
+
+// setPolicy applies a policy to sets of related files.
+type setPolicy struct {
+	wrapper
+	link func([]*File) []*File
+}
+
+// Linked returns a policy linking related files into sets, such as an archive with its checksum and manifest,
+// and applying the policy to the file representing each set. A set is kept or deleted as a whole: the summary
+// lists the representatives, with the other files in Related and their sizes added to the totals.
+func Linked(link func([]*File) []*File, policy Policy) Policy {
+	return &setPolicy{wrapper: wrapper{policy}, link: link}
+}
+
+// Apply categorizes the file representing each set.
+func (p *setPolicy) Apply(files []*File, current carbon.Carbon) *Summary {
+	return p.policy.Apply(p.link(files), current)
+}
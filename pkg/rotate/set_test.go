@@ -0,0 +1,133 @@
+/*
+Copyright The Rotate Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rotate_test
+
+import (
+	"testing"
+
+	"github.com/golang-module/carbon"
+	"github.com/raniellyferreira/rotate-files/pkg/rotate"
+	"github.com/stretchr/testify/assert"
+)
+
+// backupSet returns the archive, checksum and manifest of a backup taken at the timestamp.
+func backupSet(dir string, timestamp carbon.Carbon) []*rotate.File {
+	name := dir + "/db-" + timestamp.ToDateString()
+	return []*rotate.File{
+		{Path: name + ".tar.zst.sha256", Size: 1, Timestamp: timestamp.AddSecond()},
+		{Path: name + ".tar.zst", Size: 100, Timestamp: timestamp},
+		{Path: name + ".manifest.json", Size: 10, Timestamp: timestamp.AddSecond()},
+	}
+}
+
+func TestLinkByStem(t *testing.T) {
+	today := carbon.CreateFromDateTime(2024, 6, 15, 10, 0, 0, "UTC")
+	files := append(backupSet("/backups", today), backupSet("/backups", today.SubDay())...)
+
+	sets := rotate.LinkByStem(files)
+	assert.Equal(t, []string{"/backups/db-2024-06-15.tar.zst", "/backups/db-2024-06-14.tar.zst"}, paths(sets))
+	assert.Equal(t, []string{"/backups/db-2024-06-15.manifest.json", "/backups/db-2024-06-15.tar.zst.sha256"}, paths(sets[0].Related))
+	assert.Equal(t, int64(111), sets[0].TotalSize())
+}
+
+func TestLinkByStemDottedTimestamps(t *testing.T) {
+	files := []*rotate.File{
+		{Path: "/backups/backup.2024-06-15.tar.gz", Size: 100},
+		{Path: "/backups/backup.2024-06-15.tar.gz.sha256", Size: 1},
+		{Path: "/backups/backup.2024-06-14.tar.gz", Size: 100},
+		{Path: "/backups/full.2021.12.31T06.bak", Size: 100},
+		{Path: "/backups/full.2021.12.30T06.bak", Size: 100},
+		{Path: "/backups/full.2021.12.30T06.manifest.json", Size: 1},
+		{Path: "/backups/latest.tar.gz", Size: 100},
+		{Path: "/backups/latest.sha256", Size: 1},
+	}
+
+	// The dates are part of the stems, so each backup is a set of its own
+	sets := rotate.LinkByStem(files)
+	assert.Equal(t, []string{
+		"/backups/backup.2024-06-15.tar.gz",
+		"/backups/backup.2024-06-14.tar.gz",
+		"/backups/full.2021.12.31T06.bak",
+		"/backups/full.2021.12.30T06.bak",
+		"/backups/latest.tar.gz",
+		"/backups/latest.sha256",
+	}, paths(sets))
+	assert.Equal(t, []string{"/backups/backup.2024-06-15.tar.gz.sha256"}, paths(sets[0].Related))
+	assert.Empty(t, sets[1].Related)
+	assert.Empty(t, sets[2].Related)
+	assert.Equal(t, []string{"/backups/full.2021.12.30T06.manifest.json"}, paths(sets[3].Related))
+
+	link, err := rotate.ParseLinkBy(".sha256,.manifest.json")
+	assert.NoError(t, err)
+	sets = link(files)
+	assert.Equal(t, []string{
+		"/backups/backup.2024-06-15.tar.gz",
+		"/backups/backup.2024-06-14.tar.gz",
+		"/backups/full.2021.12.31T06.bak",
+		"/backups/full.2021.12.30T06.bak",
+		"/backups/latest.tar.gz",
+		"/backups/latest.sha256",
+	}, paths(sets))
+	assert.Equal(t, []string{"/backups/backup.2024-06-15.tar.gz.sha256"}, paths(sets[0].Related))
+	assert.Empty(t, sets[2].Related)
+	assert.Equal(t, []string{"/backups/full.2021.12.30T06.manifest.json"}, paths(sets[3].Related))
+}
+
+func TestParseLinkBy(t *testing.T) {
+	today := carbon.CreateFromDateTime(2024, 6, 15, 10, 0, 0, "UTC")
+	files := append(backupSet("/backups", today), &rotate.File{Path: "/backups/db-2024-06-14.tar.zst.sha256", Size: 1})
+
+	link, err := rotate.ParseLinkBy(".sha256, .manifest.json")
+	assert.NoError(t, err)
+	sets := link(files)
+
+	// The checksum of a deleted archive is an orphan, rotated on its own
+	assert.Equal(t, []string{"/backups/db-2024-06-15.tar.zst", "/backups/db-2024-06-14.tar.zst.sha256"}, paths(sets))
+	assert.Equal(t, []string{"/backups/db-2024-06-15.tar.zst.sha256", "/backups/db-2024-06-15.manifest.json"}, paths(sets[0].Related))
+	assert.Empty(t, sets[1].Related)
+
+	link, err = rotate.ParseLinkBy(rotate.LinkByStemName)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(link(files)))
+
+	_, err = rotate.ParseLinkBy(".sha256,,.json")
+	assert.Error(t, err)
+}
+
+func TestRotateFilesOfLinked(t *testing.T) {
+	today := carbon.CreateFromDateTime(2024, 6, 15, 10, 0, 0, "UTC")
+
+	var backups []*rotate.File
+	for i := 0; i < 5; i++ {
+		backups = append(backups, backupSet("s3://bucket/backups", today.SubDays(i))...)
+	}
+
+	scheme := &rotate.RotationScheme{KeepLast: 2, Timezone: "UTC"}
+	summary := rotate.Linked(rotate.LinkByStem, scheme).Apply(backups, today)
+
+	// Each set is kept or deleted as a whole, so no checksum or manifest is left behind
+	assert.Equal(t, []string{
+		"s3://bucket/backups/db-2024-06-15.tar.zst",
+		"s3://bucket/backups/db-2024-06-14.tar.zst",
+	}, paths(summary.Tier(rotate.TierLast).Files))
+	assert.Equal(t, int64(222), summary.Tier(rotate.TierLast).SizeTotal)
+	assert.Equal(t, 3, len(summary.ForDelete))
+	for _, file := range summary.ForDelete {
+		assert.Equal(t, 2, len(file.Related), file.Path)
+	}
+	assert.Equal(t, int64(333), summary.SizeTotalForDelete)
+}
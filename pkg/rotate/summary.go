@@ -19,32 +19,196 @@ package rotate
 import (
 	"fmt"
 	"log"
+	"slices"
+	"strings"
+
+	"github.com/raniellyferreira/rotate-files/pkg/providers"
 )
 
+// TierSummary holds the files kept by a tier and their total size.
+type TierSummary struct {
+	Name      string
+	Files     []*File
+	SizeTotal int64
+}
+
 // Summary represents the categorized backup files and their sizes.
+// Tiers lists every configured tier, built-in and user-defined; the built-in tiers are also
+// available through their own fields. Reasons tells why each kept file is kept.
+// Summaries combined from groups of files list them in Groups, each named by Group.
+// Chains lists the incremental backup chains found by a chained policy. Excluded lists the files
+// left out of rotation by the manager's filter, and InProgress those still being written. SizeReclaimed is the disk space deleting the files
+// frees, which hard links shared with kept files make smaller than their apparent size, and SharedInodes counts
+// the deleted files' inodes staying on disk through links from kept files or from files outside the listing.
+// Warnings holds the errors of a listing that still succeeded, such as providers.ErrUploadsNotListed.
 type Summary struct {
+	Group              string
+	Groups             []*Summary
+	Tiers              []*TierSummary
+	Reasons            map[*File]string
+	Chains             []*Chain
+	Minutely           []*File
 	Hourly             []*File
 	Daily              []*File
 	Weekly             []*File
 	Monthly            []*File
+	Quarterly          []*File
 	Yearly             []*File
 	ForDelete          []*File
+	Unmatched          []*File
+	Excluded           []*File
+	InProgress         []*File
+	SizeTotalMinutely  int64
 	SizeTotalHourly    int64
 	SizeTotalDaily     int64
 	SizeTotalWeekly    int64
 	SizeTotalMonthly   int64
+	SizeTotalQuarterly int64
 	SizeTotalYearly    int64
 	SizeTotalForDelete int64
+	SizeReclaimed      int64
+	SharedInodes       int
+	Warnings           []error
+}
+
+// Tier returns the summary of the named tier, or nil when the tier isn't configured.
+func (s Summary) Tier(name string) *TierSummary {
+	for _, tier := range s.Tiers {
+		if tier.Name == name {
+			return tier
+		}
+	}
+	return nil
+}
+
+// tierFiles returns the files kept by the named tier, nil when the tier isn't configured.
+func (s Summary) tierFiles(name string) []*File {
+	if tier := s.Tier(name); tier != nil {
+		return tier.Files
+	}
+	return nil
+}
+
+// addReason records why a file is kept, joining the reasons of files kept by several tiers.
+func (s *Summary) addReason(file *File, reason string) {
+	if existing, ok := s.Reasons[file]; ok {
+		if slices.Contains(strings.Split(existing, ", "), reason) {
+			return
+		}
+		reason = existing + ", " + reason
+	}
+	s.Reasons[file] = reason
+}
+
+// fill computes the files for deletion, those not kept by any tier, and the size totals.
+func (s *Summary) fill(files []*File) {
+	kept := make(map[*File]bool)
+	for _, tier := range s.Tiers {
+		for _, file := range tier.Files {
+			kept[file] = true
+		}
+	}
+
+	s.ForDelete = nil
+	for _, file := range files {
+		if !kept[file] {
+			s.ForDelete = append(s.ForDelete, file)
+		}
+	}
+	s.tally()
+}
+
+// tally fills the built-in tier fields and the size totals from the tiers and the files for deletion.
+func (s *Summary) tally() {
+	for _, tier := range s.Tiers {
+		tier.SizeTotal = sizeOf(tier.Files)
+	}
+
+	s.Minutely = s.tierFiles(TierMinutely)
+	s.Hourly = s.tierFiles(TierHourly)
+	s.Daily = s.tierFiles(TierDaily)
+	s.Weekly = s.tierFiles(TierWeekly)
+	s.Monthly = s.tierFiles(TierMonthly)
+	s.Quarterly = s.tierFiles(TierQuarterly)
+	s.Yearly = s.tierFiles(TierYearly)
+
+	s.SizeTotalMinutely = sizeOf(s.Minutely)
+	s.SizeTotalHourly = sizeOf(s.Hourly)
+	s.SizeTotalDaily = sizeOf(s.Daily)
+	s.SizeTotalWeekly = sizeOf(s.Weekly)
+	s.SizeTotalMonthly = sizeOf(s.Monthly)
+	s.SizeTotalQuarterly = sizeOf(s.Quarterly)
+	s.SizeTotalYearly = sizeOf(s.Yearly)
+	s.SizeTotalForDelete = sizeOf(s.ForDelete)
+	s.SizeReclaimed, s.SharedInodes = reclaimedSize(s.ForDelete)
+}
+
+// sizeOf returns the total size of the files, including their related files.
+func sizeOf(files []*File) int64 {
+	var total int64
+	for _, file := range files {
+		total += file.TotalSize()
+	}
+	return total
+}
+
+// reclaimedSize returns the disk space deleting the files and their related files frees: the space allocated
+// to the inodes whose every hard link is deleted, or the apparent size of the files without inodes. An inode
+// also linked from a kept file, or from a file outside the listing, stays allocated; it also returns how many do.
+func reclaimedSize(files []*File) (int64, int) {
+	type inodeKey struct{ device, number uint64 }
+	var total int64
+	inodes := make(map[inodeKey]providers.Inode)
+	deleted := make(map[inodeKey]uint64)
+	var add func(file *File)
+	add = func(file *File) {
+		if len(file.Inodes) == 0 {
+			total += file.Size
+		}
+		for _, inode := range file.Inodes {
+			key := inodeKey{inode.Device, inode.Number}
+			inodes[key] = inode
+			deleted[key]++
+		}
+		for _, related := range file.Related {
+			add(related)
+		}
+	}
+	for _, file := range files {
+		add(file)
+	}
+
+	shared := 0
+	for key, inode := range inodes {
+		if deleted[key] >= inode.Links {
+			total += inode.Allocated
+		} else {
+			shared++
+		}
+	}
+	return total, shared
+}
+
+// PrintLeftOut displays the files left out of rotation: excluded by the filter, in progress or not matching
+// the timestamp pattern.
+func (s Summary) PrintLeftOut() {
+	if len(s.Excluded) > 0 {
+		s.printExcluded()
+	}
+	if len(s.InProgress) > 0 {
+		s.printInProgress()
+	}
+	if len(s.Unmatched) > 0 {
+		s.printUnmatched()
+	}
 }
 
 // GetTotalCategorized returns the total number of categorized files in the summary.
 func (s Summary) GetTotalCategorized() int {
 	total := 0
-	total += len(s.Hourly)
-	total += len(s.Daily)
-	total += len(s.Weekly)
-	total += len(s.Monthly)
-	total += len(s.Yearly)
+	for _, tier := range s.Tiers {
+		total += len(tier.Files)
+	}
 	total += len(s.ForDelete)
 	return total
 }
@@ -52,12 +216,61 @@ func (s Summary) GetTotalCategorized() int {
 // Print displays the categorized backup files and their sizes.
 func (s Summary) Print() {
 	log.Println("")
+	s.PrintLeftOut()
+	if len(s.Chains) > 0 {
+		s.printChains()
+	}
+	if len(s.Groups) == 0 {
+		s.printTiers()
+		s.printReclaimed()
+		return
+	}
+
+	s.printGroups("")
+	log.Printf("Total: %d to delete (%s)", len(s.ForDelete), s.formatSize(s.SizeTotalForDelete))
+	s.printReclaimed()
+}
+
+// printReclaimed displays the space deleting actually frees, when hard links or allocation make it
+// differ from the apparent size. Hard links are only blamed when a deleted file's inode stays on disk.
+func (s Summary) printReclaimed() {
+	if s.SizeReclaimed == s.SizeTotalForDelete {
+		return
+	}
+	if s.SharedInodes == 0 {
+		log.Printf("Apparent size: %s, space reclaimed: %s", s.formatSize(s.SizeTotalForDelete), s.formatSize(s.SizeReclaimed))
+		return
+	}
+	log.Printf("Apparent size: %s, space reclaimed: %s (%d inodes still linked elsewhere)",
+		s.formatSize(s.SizeTotalForDelete), s.formatSize(s.SizeReclaimed), s.SharedInodes)
+}
+
+// printGroups displays each group, naming nested groups after their parents.
+func (s Summary) printGroups(prefix string) {
+	for _, group := range s.Groups {
+		name := group.Group
+		if name == "" {
+			name = "(ungrouped)"
+		}
+		name = prefix + name
+		if len(group.Groups) > 0 {
+			group.printGroups(name + " / ")
+			continue
+		}
+		log.Printf("==== %s: %d to delete (%s)", name, len(group.ForDelete), s.formatSize(group.SizeTotalForDelete))
+		log.Println("")
+		group.printTiers()
+	}
+}
+
+// printTiers displays the files for deletion and the files kept by each tier.
+func (s Summary) printTiers() {
 	s.printBackups("Delete", s.ForDelete, s.SizeTotalForDelete)
-	s.printBackups("Yearly", s.Yearly, s.SizeTotalYearly)
-	s.printBackups("Monthly", s.Monthly, s.SizeTotalMonthly)
-	s.printBackups("Weekly", s.Weekly, s.SizeTotalWeekly)
-	s.printBackups("Daily", s.Daily, s.SizeTotalDaily)
-	s.printBackups("Hourly", s.Hourly, s.SizeTotalHourly)
+	// Reverse order, so yearly is printed before hourly
+	for i := len(s.Tiers) - 1; i >= 0; i-- {
+		tier := s.Tiers[i]
+		s.printBackups(strings.ToUpper(tier.Name[:1])+tier.Name[1:], tier.Files, tier.SizeTotal)
+	}
 }
 
 // printBackups displays the backup files in the specified category.
@@ -68,13 +281,74 @@ func (s Summary) printBackups(category string, backups []*File, sizeTotal int64)
 		log.Println("  No files")
 	} else {
 		for _, v := range backups {
-			log.Println(" ", v.Path, s.formatSize(v.Size), v.Timestamp)
+			path := v.Path
+			if v.Directory {
+				path += "/"
+			}
+			line := []any{" ", path, s.formatSize(v.TotalSize()), v.Timestamp}
+			if v.TimestampSource != "" {
+				line = append(line, "("+v.TimestampSource+")")
+			}
+			// The reason is only worth printing when it says more than the category
+			if reason, ok := s.Reasons[v]; ok && !strings.EqualFold(reason, category) {
+				line = append(line, "["+reason+"]")
+			}
+			log.Println(line...)
+			for _, related := range v.Related {
+				log.Println("    +", related.Path, s.formatSize(related.Size))
+			}
 		}
 		log.Printf("  Total Size: %s", formattedSize)
 	}
 	log.Println("")
 }
 
+// printUnmatched displays the files ignored because their path doesn't match the timestamp pattern.
+func (s Summary) printUnmatched() {
+	log.Printf("Unmatched timestamp pattern, ignored [%d]:", len(s.Unmatched))
+	for _, v := range s.Unmatched {
+		log.Println(" ", v.Path, s.formatSize(v.Size))
+	}
+	log.Println("")
+}
+
+// printExcluded displays the files left out of rotation by the filters.
+func (s Summary) printExcluded() {
+	log.Printf("Excluded by filters, ignored [%d]:", len(s.Excluded))
+	for _, v := range s.Excluded {
+		log.Println(" ", v.Path, s.formatSize(v.Size))
+	}
+	log.Println("")
+}
+
+// printInProgress displays the files skipped because they are still being written or uploaded.
+func (s Summary) printInProgress() {
+	log.Printf("In progress, ignored [%d]:", len(s.InProgress))
+	for _, v := range s.InProgress {
+		log.Println(" ", v.Path, s.formatSize(v.Size))
+	}
+	log.Println("")
+}
+
+// printChains displays the incremental backup chains, flagging the broken ones.
+func (s Summary) printChains() {
+	broken := 0
+	for _, chain := range s.Chains {
+		if chain.Broken {
+			broken++
+		}
+	}
+	log.Printf("Backup chains [%d], broken [%d]:", len(s.Chains), broken)
+	for _, chain := range s.Chains {
+		line := []any{" ", chain.Files[0].Path, fmt.Sprintf("+ %d incremental(s)", len(chain.Files)-1)}
+		if chain.Broken {
+			line = append(line, "[broken: parent missing]")
+		}
+		log.Println(line...)
+	}
+	log.Println("")
+}
+
 // formatSize converts the size in bytes to a human-readable format.
 func (s Summary) formatSize(size int64) string {
 	const unit = 1024
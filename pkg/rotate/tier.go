@@ -0,0 +1,296 @@
+/*
+Copyright The Rotate Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rotate
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang-module/carbon"
+)
+
+// Names of the built-in tiers, as reported in the summary.
+const (
+	TierMinutely  = "minutely"
+	TierHourly    = "hourly"
+	TierDaily     = "daily"
+	TierWeekly    = "weekly"
+	TierMonthly   = "monthly"
+	TierQuarterly = "quarterly"
+	TierYearly    = "yearly"
+
+	TierLast        = "last"
+	TierWithin      = "within"
+	TierWithinDaily = "within-daily"
+)
+
+// Day, Week and Year are the units ParseDuration accepts besides those of time.ParseDuration.
+const (
+	Day  = 24 * time.Hour
+	Week = 7 * Day
+	Year = 365 * Day
+)
+
+// Tier is a user-defined tier keeping one backup per Interval, for the newest Count periods.
+// A negative count keeps every period. Periods are aligned to the wall clock of the scheme's
+// time zone, counted from the Unix epoch. Selection picks the backup representing each period,
+// the newest when empty.
+type Tier struct {
+	Name      string
+	Interval  time.Duration
+	Count     int
+	Selection string
+}
+
+// durationUnit matches one number and unit of a duration such as "1d12h".
+var durationUnit = regexp.MustCompile(`(\d+(?:\.\d*)?|\.\d+)([a-zµ]+)`)
+
+// ParseDuration is like time.ParseDuration but also accepts days ("d"), weeks ("w") and
+// years of 365 days ("y"), e.g. "30d" or "1y6w". Durations beyond time.Duration's range, about 292 years, are rejected.
+func ParseDuration(value string) (time.Duration, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, fmt.Errorf("invalid duration %q", value)
+	}
+
+	var total time.Duration
+	rest := value
+	for rest != "" {
+		match := durationUnit.FindStringSubmatchIndex(rest)
+		if match == nil || match[0] != 0 {
+			return 0, fmt.Errorf("invalid duration %q", value)
+		}
+		number, unit := rest[match[2]:match[3]], rest[match[4]:match[5]]
+		rest = rest[match[1]:]
+
+		var scale time.Duration
+		switch unit {
+		case "d":
+			scale = Day
+		case "w":
+			scale = Week
+		case "y":
+			scale = Year
+		default:
+			d, err := time.ParseDuration(number + unit)
+			if err != nil {
+				return 0, fmt.Errorf("invalid duration %q: %w", value, err)
+			}
+			if d > math.MaxInt64-total {
+				return 0, fmt.Errorf("invalid duration %q: out of range", value)
+			}
+			total += d
+			continue
+		}
+		n, err := strconv.ParseFloat(number, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid duration %q", value)
+		}
+		if n*float64(scale) >= float64(math.MaxInt64-total) {
+			return 0, fmt.Errorf("invalid duration %q: out of range", value)
+		}
+		total += time.Duration(n * float64(scale))
+	}
+	return total, nil
+}
+
+// ParseTiers parses a comma-separated list of tiers written as duration:count, such as
+// "6h:8,1d:14,30d:24,365d:10". A tier may add its selection strategy, as in "30d:24:oldest".
+// Tiers are named after their duration.
+func ParseTiers(value string) ([]Tier, error) {
+	var tiers []Tier
+	for _, token := range strings.Split(value, ",") {
+		token = strings.TrimSpace(token)
+		parts := strings.Split(token, ":")
+		if len(parts) < 2 || len(parts) > 3 {
+			return nil, fmt.Errorf("%w: %q, expected duration:count", ErrInvalidTier, token)
+		}
+
+		interval, err := ParseDuration(parts[0])
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidTier, err)
+		}
+		count, err := strconv.Atoi(strings.TrimSpace(parts[1]))
+		if err != nil {
+			return nil, fmt.Errorf("%w: %q, invalid count", ErrInvalidTier, token)
+		}
+
+		tier := Tier{Name: strings.TrimSpace(parts[0]), Interval: interval, Count: count}
+		if len(parts) == 3 {
+			tier.Selection = strings.TrimSpace(parts[2])
+		}
+		tiers = append(tiers, tier)
+	}
+
+	if err := validateTiers(tiers); err != nil {
+		return nil, err
+	}
+	return tiers, nil
+}
+
+// validateTiers checks that the user-defined tiers have a unique name, a positive interval and a known selection.
+func validateTiers(tiers []Tier) error {
+	seen := map[string]bool{
+		TierMinutely: true, TierHourly: true, TierDaily: true, TierWeekly: true,
+		TierMonthly: true, TierQuarterly: true, TierYearly: true,
+		TierLast: true, TierWithin: true, TierWithinDaily: true, TierExponential: true, TierHanoi: true, TierChain: true,
+	}
+	for _, tier := range tiers {
+		if tier.Name == "" || seen[tier.Name] {
+			return fmt.Errorf("%w: duplicate or empty name %q", ErrInvalidTier, tier.Name)
+		}
+		seen[tier.Name] = true
+
+		if tier.Interval <= 0 {
+			return fmt.Errorf("%w: %s: interval must be positive", ErrInvalidTier, tier.Name)
+		}
+		if err := validateSelection(tier.Selection); err != nil {
+			return fmt.Errorf("%w: %s: %w", ErrInvalidTier, tier.Name, err)
+		}
+	}
+	return nil
+}
+
+// durationBucket returns the bucket key of periods of the given length, aligned to the wall clock.
+func durationBucket(interval time.Duration) func(carbon.Carbon) string {
+	return func(c carbon.Carbon) string {
+		return strconv.FormatInt(wallPeriod(c, interval), 10)
+	}
+}
+
+// wallPeriod returns the index of the period of the given length holding the timestamp,
+// counting periods of its wall clock from the Unix epoch.
+func wallPeriod(c carbon.Carbon, interval time.Duration) int64 {
+	t := c.ToStdTime()
+	_, offset := t.Zone()
+	wall := t.Unix() + int64(offset)
+	seconds := max(int64(interval/time.Second), 1)
+	period := wall / seconds
+	if wall < 0 && wall%seconds != 0 {
+		period--
+	}
+	return period
+}
+
+// tierRule is how a tier is evaluated: files within the window are grouped into buckets,
+// keeping a representative per bucket, newest buckets first, up to the limit. A nil bucket
+// puts every file in its own bucket. The reason, when set, explains why a file is kept instead of the name.
+type tierRule struct {
+	name     string
+	window   func(*File) bool
+	bucket   func(carbon.Carbon) string
+	strategy string
+	limit    int
+	reason   func(*File) string
+}
+
+// tierRules maps the built-in tiers, the user-defined ones and the keep rules onto rules, skipping tiers
+// with a zero limit. The exponential and Hanoi modes replace the tiers with their own rule.
+//
+// In calendar mode the tiers have no window: buckets only fall out of a tier when newer buckets fill it,
+// never because time passed. Without a selection strategy each bucket keeps its oldest file, which later
+// backups of the same period never replace, even while the period is still open. So a later run never
+// deletes a file kept by an earlier run for a period that still has room in its tier.
+func (s *RotationScheme) tierRules(files Files, current carbon.Carbon) []tierRule {
+	selection := s.Selection
+	rules := []tierRule{
+		{name: TierMinutely, window: func(file *File) bool {
+			return file.IsMinutelyOf(current, nil, s.Minutely, s.MinutelyInterval)
+		}, bucket: minuteBucket(s.MinutelyInterval), strategy: selection.Minutely, limit: s.Minutely},
+		{name: TierHourly, window: func(file *File) bool {
+			return file.IsHourlyOf(current, nil, s.Hourly)
+		}, bucket: hourBucket, strategy: selection.Hourly, limit: s.Hourly},
+		{name: TierDaily, window: func(file *File) bool {
+			return file.IsDailyOf(current, nil, s.Daily)
+		}, bucket: dayBucket, strategy: selection.Daily, limit: s.Daily},
+		{name: TierWeekly, window: func(file *File) bool {
+			return file.IsWeeklyOf(current, nil, s.Weekly)
+		}, bucket: weekBucket, strategy: selection.Weekly, limit: s.Weekly},
+		{name: TierMonthly, window: func(file *File) bool {
+			return file.IsMonthlyOf(current, nil, s.Monthly)
+		}, bucket: monthBucket, strategy: selection.Monthly, limit: s.Monthly},
+		{name: TierQuarterly, window: func(file *File) bool {
+			return file.IsQuarterlyOf(current, nil, s.Quarterly, s.FiscalYearStart)
+		}, bucket: quarterBucket(s.FiscalYearStart), strategy: selection.Quarterly, limit: s.Quarterly},
+		{name: TierYearly, window: func(file *File) bool {
+			return file.IsFiscalYearlyOf(current, nil, s.FiscalYearStart)
+		}, bucket: yearBucket(s.FiscalYearStart), strategy: selection.Yearly, limit: s.Yearly},
+	}
+
+	for _, tier := range s.Tiers {
+		rule := tierRule{name: tier.Name, bucket: durationBucket(tier.Interval), strategy: tier.Selection, limit: tier.Count}
+		if tier.Count >= 0 {
+			rule.window = withinPeriods(current, tier.Interval, tier.Count)
+		}
+		rules = append(rules, rule)
+	}
+
+	switch s.Mode {
+	case ModeExponential:
+		rules = []tierRule{s.exponentialRule(current)}
+	case ModeHanoi:
+		rules = []tierRule{s.hanoiRule(files)}
+	}
+
+	kept := rules[:0]
+	for _, rule := range rules {
+		if rule.limit == 0 {
+			continue
+		}
+		if s.Mode == ModeCalendar {
+			rule.window = nil
+			if rule.strategy == "" {
+				rule.strategy = SelectOldest
+			}
+		}
+		kept = append(kept, rule)
+	}
+
+	// The keep rules ignore periods, so they apply the same way in every mode.
+	if s.KeepLast > 0 {
+		kept = append(kept, tierRule{name: TierLast, limit: s.KeepLast})
+	}
+	if s.KeepWithin > 0 {
+		kept = append(kept, tierRule{name: TierWithin, window: within(current, s.KeepWithin), limit: -1})
+	}
+	if s.KeepWithinDaily > 0 {
+		kept = append(kept, tierRule{
+			name: TierWithinDaily, window: within(current, s.KeepWithinDaily), bucket: dayBucket, strategy: selection.Daily, limit: -1,
+		})
+	}
+	return kept
+}
+
+// withinPeriods returns a window accepting the files less than count periods of the interval old. It compares
+// whole periods, so long tiers such as 300 periods of a year don't overflow a time.Duration.
+func withinPeriods(current carbon.Carbon, interval time.Duration, count int) func(*File) bool {
+	seconds := max(int64(interval/time.Second), 1)
+	return func(file *File) bool {
+		return file.Timestamp.DiffInSeconds(current)/seconds < int64(count)
+	}
+}
+
+// within returns a window accepting the files not older than the duration.
+func within(current carbon.Carbon, d time.Duration) func(*File) bool {
+	return func(file *File) bool {
+		return file.Timestamp.DiffInSeconds(current) <= int64(d/time.Second)
+	}
+}
@@ -0,0 +1,232 @@
+/*
+Copyright The Rotate Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rotate_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/golang-module/carbon"
+	"github.com/raniellyferreira/rotate-files/pkg/rotate"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseDuration(t *testing.T) {
+	for value, expected := range map[string]time.Duration{
+		"90m":    90 * time.Minute,
+		"6h":     6 * time.Hour,
+		"30d":    30 * rotate.Day,
+		"2w":     2 * rotate.Week,
+		"1y6w":   rotate.Year + 6*rotate.Week,
+		"1.5d":   36 * time.Hour,
+		"1d12h":  36 * time.Hour,
+		"365d":   rotate.Year,
+		"1h30m0": 0,
+	} {
+		d, err := rotate.ParseDuration(value)
+		if expected == 0 {
+			assert.Error(t, err, value)
+			continue
+		}
+		assert.NoError(t, err, value)
+		assert.Equal(t, expected, d, value)
+	}
+
+	for _, value := range []string{"", "d", "10", "5x", "-1d", "300y", "1y292y", "106752d", "2562048h", "292y2562047h"} {
+		_, err := rotate.ParseDuration(value)
+		assert.Error(t, err, value)
+	}
+}
+
+func TestParseTiers(t *testing.T) {
+	tiers, err := rotate.ParseTiers("6h:8, 1d:14,30d:24:oldest,365d:-1")
+	assert.NoError(t, err)
+	assert.Equal(t, []rotate.Tier{
+		{Name: "6h", Interval: 6 * time.Hour, Count: 8},
+		{Name: "1d", Interval: rotate.Day, Count: 14},
+		{Name: "30d", Interval: 30 * rotate.Day, Count: 24, Selection: rotate.SelectOldest},
+		{Name: "365d", Interval: rotate.Year, Count: -1},
+	}, tiers)
+
+	for _, value := range []string{"6h", "6h:x", "0h:3", "6h:1,6h:2", "6h:1:biggest", "1d:1:newest:x", "300y:1"} {
+		_, err := rotate.ParseTiers(value)
+		assert.ErrorIs(t, err, rotate.ErrInvalidTier, value)
+	}
+}
+
+func TestRotateFilesOfLongTiers(t *testing.T) {
+	today := carbon.CreateFromDateTime(2024, 6, 15, 10, 0, 0, "UTC")
+
+	var backups []*rotate.File
+	for i := 0; i < 5; i++ {
+		timestamp := today.SubYears(i * 10)
+		backups = append(backups, &rotate.File{Path: timestamp.ToDateString(), Timestamp: timestamp})
+	}
+
+	// 300 yearly and 4000 monthly periods span more than a time.Duration holds, and keep every backup
+	tiers, err := rotate.ParseTiers("365d:300,30d:4000")
+	assert.NoError(t, err)
+	summary := rotate.RotateFilesOf(backups, &rotate.RotationScheme{Tiers: tiers, Timezone: "UTC"}, today)
+	assert.Equal(t, 5, len(summary.Tier("365d").Files))
+	assert.Equal(t, 5, len(summary.Tier("30d").Files))
+	assert.Empty(t, summary.ForDelete)
+}
+
+func TestRotateFilesOfCustomTiers(t *testing.T) {
+	today := carbon.CreateFromDateTime(2024, 6, 15, 10, 0, 0, "UTC")
+
+	// A backup every 3 hours over the last ten days
+	var backups []*rotate.File
+	for i := 0; i < 80; i++ {
+		timestamp := today.SubHours(i * 3)
+		backups = append(backups, &rotate.File{Path: timestamp.ToDateTimeString(), Timestamp: timestamp, Size: 1})
+	}
+
+	tiers, err := rotate.ParseTiers("6h:8,1d:14")
+	assert.NoError(t, err)
+
+	scheme := rotate.RotationScheme{Tiers: tiers, Timezone: "UTC"}
+	summary := rotate.RotateFilesOf(backups, &scheme, today)
+
+	assert.Equal(t, 2, len(summary.Tiers))
+	assert.Nil(t, summary.Tier(rotate.TierHourly))
+	assert.Nil(t, summary.Hourly)
+
+	sixHours := summary.Tier("6h")
+	assert.Equal(t, []string{
+		"2024-06-15 10:00:00",
+		"2024-06-15 04:00:00",
+		"2024-06-14 22:00:00",
+		"2024-06-14 16:00:00",
+		"2024-06-14 10:00:00",
+		"2024-06-14 04:00:00",
+		"2024-06-13 22:00:00",
+		"2024-06-13 16:00:00",
+	}, paths(sixHours.Files))
+	assert.Equal(t, int64(8), sixHours.SizeTotal)
+
+	daily := summary.Tier("1d")
+	assert.Equal(t, 11, len(daily.Files))
+	assert.Equal(t, "2024-06-14 22:00:00", daily.Files[1].Path)
+	assert.Equal(t, "2024-06-05 22:00:00", daily.Files[10].Path)
+
+	// Three backups represent both a 6 hours period and a day
+	assert.Equal(t, len(backups)-8-11+3, len(summary.ForDelete))
+	assert.Equal(t, len(backups)+3, summary.GetTotalCategorized())
+}
+
+func TestRotateFilesOfCustomTiersCalendar(t *testing.T) {
+	today := carbon.CreateFromDateTime(2024, 6, 15, 10, 0, 0, "UTC")
+
+	backups := []*rotate.File{
+		{Path: "/2020-03", Timestamp: carbon.CreateFromDateTime(2020, 3, 1, 0, 0, 0, "UTC")},
+		{Path: "/2020-02", Timestamp: carbon.CreateFromDateTime(2020, 2, 1, 0, 0, 0, "UTC")},
+		{Path: "/2020-01", Timestamp: carbon.CreateFromDateTime(2020, 1, 1, 0, 0, 0, "UTC")},
+	}
+
+	scheme := rotate.RotationScheme{
+		Tiers:    []rotate.Tier{{Name: "month", Interval: 30 * rotate.Day, Count: 2}},
+		Timezone: "UTC",
+		Mode:     rotate.ModeCalendar,
+	}
+	summary := rotate.RotateFilesOf(backups, &scheme, today)
+
+	assert.Equal(t, []string{"/2020-03", "/2020-02"}, paths(summary.Tier("month").Files))
+	assert.Equal(t, []string{"/2020-01"}, paths(summary.ForDelete))
+}
+
+func TestRotateFilesOfBuiltinTiers(t *testing.T) {
+	today := carbon.CreateFromDateTime(2024, 6, 15, 10, 0, 0, "UTC")
+
+	backups := []*rotate.File{
+		{Path: "/today", Timestamp: today.SubHours(1)},
+		{Path: "/yesterday", Timestamp: today.SubDays(1)},
+	}
+
+	scheme := rotate.RotationScheme{Hourly: 24, Daily: 7, Timezone: "UTC"}
+	summary := rotate.RotateFilesOf(backups, &scheme, today)
+
+	// Only the configured built-in tiers are reported, mirrored by their fields
+	assert.Equal(t, 2, len(summary.Tiers))
+	assert.Equal(t, summary.Hourly, summary.Tier(rotate.TierHourly).Files)
+	assert.Equal(t, summary.Daily, summary.Tier(rotate.TierDaily).Files)
+	assert.Nil(t, summary.Tier(rotate.TierWeekly))
+}
+
+func TestRotateFilesOfKeepLast(t *testing.T) {
+	today := carbon.CreateFromDateTime(2024, 6, 15, 10, 0, 0, "UTC")
+
+	// A burst of manual backups in the same hour
+	backups := []*rotate.File{
+		{Path: "/manual-3", Timestamp: today.SubMinutes(5)},
+		{Path: "/manual-2", Timestamp: today.SubMinutes(10)},
+		{Path: "/manual-1b", Timestamp: today.SubMinutes(15)},
+		{Path: "/manual-1a", Timestamp: today.SubMinutes(15)},
+		{Path: "/nightly", Timestamp: today.SubHours(8)},
+	}
+
+	scheme := rotate.RotationScheme{Hourly: 24, KeepLast: 4, Timezone: "UTC"}
+	summary := rotate.RotateFilesOf(backups, &scheme, today)
+
+	assert.Equal(t, []string{"/manual-3", "/nightly"}, paths(summary.Hourly))
+	assert.Equal(t, []string{"/manual-3", "/manual-2", "/manual-1a", "/manual-1b"}, paths(summary.Tier(rotate.TierLast).Files))
+	assert.Empty(t, summary.ForDelete)
+}
+
+func TestRotateFilesOfKeepWithin(t *testing.T) {
+	today := carbon.CreateFromDateTime(2024, 6, 15, 10, 0, 0, "UTC")
+
+	// A backup every 6 hours over the last five days
+	var backups []*rotate.File
+	for i := 0; i < 20; i++ {
+		timestamp := today.SubHours(i * 6)
+		backups = append(backups, &rotate.File{Path: timestamp.ToDateTimeString(), Timestamp: timestamp})
+	}
+
+	scheme := rotate.RotationScheme{KeepWithin: 48 * time.Hour, KeepWithinDaily: 4 * rotate.Day, Timezone: "UTC"}
+	summary := rotate.RotateFilesOf(backups, &scheme, today)
+
+	within := summary.Tier(rotate.TierWithin).Files
+	assert.Equal(t, 9, len(within))
+	assert.Equal(t, "2024-06-13 10:00:00", within[8].Path)
+
+	assert.Equal(t, []string{
+		"2024-06-15 10:00:00",
+		"2024-06-14 22:00:00",
+		"2024-06-13 22:00:00",
+		"2024-06-12 22:00:00",
+		"2024-06-11 22:00:00",
+	}, paths(summary.Tier(rotate.TierWithinDaily).Files))
+
+	assert.Equal(t, 20-9-2, len(summary.ForDelete))
+}
+
+func TestRotateFilesOfKeepWithinCalendar(t *testing.T) {
+	today := carbon.CreateFromDateTime(2024, 6, 15, 10, 0, 0, "UTC")
+
+	backups := []*rotate.File{
+		{Path: "/recent", Timestamp: today.SubHours(1)},
+		{Path: "/old", Timestamp: today.SubDays(30)},
+	}
+
+	// The keep window still applies in calendar mode
+	scheme := rotate.RotationScheme{KeepWithin: 48 * time.Hour, Timezone: "UTC", Mode: rotate.ModeCalendar}
+	summary := rotate.RotateFilesOf(backups, &scheme, today)
+
+	assert.Equal(t, []string{"/recent"}, paths(summary.Tier(rotate.TierWithin).Files))
+	assert.Equal(t, []string{"/old"}, paths(summary.ForDelete))
+}
@@ -0,0 +1,234 @@
+/*
+Copyright The Rotate Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rotate
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang-module/carbon"
+)
+
+// strftimeDirective describes the regexp group produced by a strftime directive.
+type strftimeDirective struct {
+	group string
+	expr  string
+}
+
+// strftimeDirectives maps the supported strftime directives to their named regexp groups.
+var strftimeDirectives = map[byte]strftimeDirective{
+	'Y': {"year", `\d{4}`},
+	'y': {"year2", `\d{2}`},
+	'm': {"month", `\d{2}`},
+	'd': {"day", `\d{2}`},
+	'j': {"yday", `\d{3}`},
+	'H': {"hour", `\d{2}`},
+	'M': {"minute", `\d{2}`},
+	'S': {"second", `\d{2}`},
+	's': {"epoch", `\d+`},
+}
+
+// TimestampPattern extracts backup timestamps from file names or paths.
+//
+// A pattern is either strftime-style (e.g. "db-%Y%m%d-%H%M.sql.gz" or the
+// date-folder layout "backups/%Y/%m/%d/") or a regular expression with named
+// groups (year, month, day, hour, minute, second or epoch).
+type TimestampPattern struct {
+	pattern  string
+	re       *regexp.Regexp
+	fullPath bool
+}
+
+// NewTimestampPattern compiles a strftime-style or named-group regexp pattern.
+func NewTimestampPattern(pattern string) (*TimestampPattern, error) {
+	if strings.TrimSpace(pattern) == "" {
+		return nil, fmt.Errorf("empty timestamp pattern")
+	}
+
+	if strings.Contains(pattern, "(?P<") || strings.Contains(pattern, "(?<") {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid timestamp pattern %q: %w", pattern, err)
+		}
+		if !hasGroup(re, "year") && !hasGroup(re, "year2") && !hasGroup(re, "epoch") {
+			return nil, fmt.Errorf("invalid timestamp pattern %q: missing year or epoch group", pattern)
+		}
+		return &TimestampPattern{pattern: pattern, re: re, fullPath: true}, nil
+	}
+
+	expr, err := strftimeToRegexp(pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	// Patterns spanning directories are matched against the path, anchored at a
+	// path segment; plain patterns must match the whole base name.
+	fullPath := strings.Contains(pattern, "/")
+	if fullPath {
+		expr = `(?:^|/)` + expr
+		if !strings.HasSuffix(pattern, "/") {
+			expr += `$`
+		}
+	} else {
+		expr = `^` + expr + `$`
+	}
+
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid timestamp pattern %q: %w", pattern, err)
+	}
+	return &TimestampPattern{pattern: pattern, re: re, fullPath: fullPath}, nil
+}
+
+// String returns the pattern as given by the user.
+func (p *TimestampPattern) String() string {
+	return p.pattern
+}
+
+// Parse extracts the timestamp from the given path in the local time zone, reporting whether the path matched the pattern.
+func (p *TimestampPattern) Parse(path string) (carbon.Carbon, bool) {
+	return p.ParseInLocation(path, time.Local)
+}
+
+// ParseInLocation is like Parse but interprets the date and time found in the path in the given location.
+func (p *TimestampPattern) ParseInLocation(path string, loc *time.Location) (carbon.Carbon, bool) {
+	subject := path
+	if !p.fullPath {
+		subject = baseName(path)
+	}
+
+	match := p.re.FindStringSubmatch(subject)
+	if match == nil {
+		return carbon.Carbon{}, false
+	}
+
+	values := make(map[string]int)
+	for i, name := range p.re.SubexpNames() {
+		if name == "" || match[i] == "" {
+			continue
+		}
+		n, err := strconv.Atoi(match[i])
+		if err != nil {
+			return carbon.Carbon{}, false
+		}
+		values[name] = n
+	}
+
+	t, ok := buildTime(values, loc)
+	if !ok {
+		return carbon.Carbon{}, false
+	}
+	return carbon.FromStdTime(t), true
+}
+
+// strftimeToRegexp converts a strftime-style pattern into a regular expression.
+func strftimeToRegexp(pattern string) (string, error) {
+	var sb strings.Builder
+	seen := make(map[byte]bool)
+
+	for i := 0; i < len(pattern); i++ {
+		c := pattern[i]
+		switch {
+		case c == '%':
+			if i+1 >= len(pattern) {
+				return "", fmt.Errorf("invalid timestamp pattern %q: trailing %%", pattern)
+			}
+			i++
+			d := pattern[i]
+			if d == '%' {
+				sb.WriteString("%")
+				continue
+			}
+			directive, ok := strftimeDirectives[d]
+			if !ok {
+				return "", fmt.Errorf("invalid timestamp pattern %q: unsupported directive %%%c", pattern, d)
+			}
+			if seen[d] {
+				// Repeated directives (e.g. "%Y/%Y%m%d") only capture their first occurrence.
+				sb.WriteString(`(?:` + directive.expr + `)`)
+				continue
+			}
+			seen[d] = true
+			sb.WriteString(`(?P<` + directive.group + `>` + directive.expr + `)`)
+		case c == '*':
+			sb.WriteString(`[^/]*`)
+		default:
+			sb.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+
+	if !seen['Y'] && !seen['y'] && !seen['s'] {
+		return "", fmt.Errorf("invalid timestamp pattern %q: missing %%Y, %%y or %%s", pattern)
+	}
+	return sb.String(), nil
+}
+
+// buildTime assembles a time from the parsed pattern groups, rejecting out-of-range values.
+func buildTime(values map[string]int, loc *time.Location) (time.Time, bool) {
+	if epoch, ok := values["epoch"]; ok {
+		return time.Unix(int64(epoch), 0).In(loc), true
+	}
+
+	year, ok := values["year"]
+	if !ok {
+		year2, ok := values["year2"]
+		if !ok {
+			return time.Time{}, false
+		}
+		year = 2000 + year2
+	}
+
+	month, day := 1, 1
+	if v, ok := values["month"]; ok {
+		month = v
+	}
+	if v, ok := values["day"]; ok {
+		day = v
+	}
+	hour, minute, second := values["hour"], values["minute"], values["second"]
+
+	if yday, ok := values["yday"]; ok {
+		if yday < 1 || yday > 366 {
+			return time.Time{}, false
+		}
+		t := time.Date(year, time.January, yday, hour, minute, second, 0, loc)
+		return t, t.Year() == year
+	}
+
+	t := time.Date(year, time.Month(month), day, hour, minute, second, 0, loc)
+	if t.Year() != year || int(t.Month()) != month || t.Day() != day ||
+		hour > 23 || minute > 59 || second > 59 {
+		return time.Time{}, false
+	}
+	return t, true
+}
+
+// hasGroup reports whether the regexp defines the named group.
+func hasGroup(re *regexp.Regexp, name string) bool {
+	return re.SubexpIndex(name) >= 0
+}
+
+// baseName returns the last element of a local or cloud path.
+func baseName(path string) string {
+	if i := strings.LastIndexAny(path, `/\`); i >= 0 {
+		return path[i+1:]
+	}
+	return path
+}
@@ -0,0 +1,125 @@
+/*
+Copyright The Rotate Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rotate_test
+
+import (
+	"testing"
+
+	"github.com/golang-module/carbon"
+	"github.com/raniellyferreira/rotate-files/pkg/rotate"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTimestampPattern_Parse(t *testing.T) {
+	tests := []struct {
+		name     string
+		pattern  string
+		path     string
+		expected string
+		matched  bool
+	}{
+		{
+			name:     "Strftime file name",
+			pattern:  "db-%Y%m%d-%H%M.sql.gz",
+			path:     "/backups/db-20240105-2330.sql.gz",
+			expected: "2024-01-05 23:30:00",
+			matched:  true,
+		},
+		{
+			name:     "Strftime on cloud path",
+			pattern:  "db-%Y%m%d-%H%M.sql.gz",
+			path:     "s3://bucket/backups/db-20240105-2330.sql.gz",
+			expected: "2024-01-05 23:30:00",
+			matched:  true,
+		},
+		{
+			name:    "Strftime not matching",
+			pattern: "db-%Y%m%d-%H%M.sql.gz",
+			path:    "/backups/db-20240105-2330.sql.gz.sha256",
+			matched: false,
+		},
+		{
+			name:    "Strftime with invalid date",
+			pattern: "db-%Y%m%d-%H%M.sql.gz",
+			path:    "/backups/db-20241332-2330.sql.gz",
+			matched: false,
+		},
+		{
+			name:     "Date folder layout",
+			pattern:  "backups/%Y/%m/%d/",
+			path:     "gs://bucket/backups/2023/07/14/dump.tar",
+			expected: "2023-07-14 00:00:00",
+			matched:  true,
+		},
+		{
+			name:    "Date folder layout with other prefix",
+			pattern: "backups/%Y/%m/%d/",
+			path:    "gs://bucket/old-backups/2023/07/14/dump.tar",
+			matched: false,
+		},
+		{
+			name:     "Wildcard",
+			pattern:  "*_%Y-%m-%d.tar",
+			path:     "/data/orders_2022-02-28.tar",
+			expected: "2022-02-28 00:00:00",
+			matched:  true,
+		},
+		{
+			name:     "Regexp with named groups",
+			pattern:  `(?P<year>\d{4})\.(?P<month>\d{2})\.(?P<day>\d{2})T(?P<hour>\d{2})`,
+			path:     "/backups/full.2021.12.31T06.bak",
+			expected: "2021-12-31 06:00:00",
+			matched:  true,
+		},
+		{
+			name:     "Epoch",
+			pattern:  "snap-%s.img",
+			path:     "/snaps/snap-1700000000.img",
+			expected: carbon.CreateFromTimestamp(1700000000).ToDateTimeString(),
+			matched:  true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			pattern, err := rotate.NewTimestampPattern(test.pattern)
+			assert.NoError(t, err)
+
+			timestamp, ok := pattern.Parse(test.path)
+			assert.Equal(t, test.matched, ok)
+			if test.matched {
+				assert.Equal(t, test.expected, timestamp.ToDateTimeString())
+			}
+		})
+	}
+}
+
+func TestNewTimestampPattern_Invalid(t *testing.T) {
+	patterns := []string{
+		"",
+		"db-%m%d.sql",
+		"db-%Y%q.sql",
+		"db-%Y%",
+		`(?P<month>\d{2})`,
+		`(?P<year>\d{4}`,
+	}
+
+	for _, pattern := range patterns {
+		_, err := rotate.NewTimestampPattern(pattern)
+		assert.Error(t, err, pattern)
+	}
+}